@@ -12,10 +12,27 @@ import (
 	"github.com/tchajed/goose/internal/coq"
 )
 
+// writeFile writes a Coq file to outFile, creating its directory if needed
+func writeFile(f coq.File, outFile string) error {
+	outDir := path.Dir(outFile)
+	_, err := os.Stat(outDir)
+	if os.IsNotExist(err) {
+		os.MkdirAll(outDir, 0777)
+	}
+	out, err := os.Create(outFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	f.Write(out)
+	return nil
+}
+
 //noinspection GoUnhandledErrorResult
 func main() {
 	flag.Usage = func() {
 		fmt.Fprintln(flag.CommandLine.Output(), "Usage: goose [options] <path to go package>")
+		fmt.Fprintln(flag.CommandLine.Output(), "       goose [options] -packages -out <dir> <package pattern>...")
 
 		flag.PrintDefaults()
 	}
@@ -24,6 +41,8 @@ func main() {
 		"add comments indicating Go source code location for each top-level declaration")
 	flag.BoolVar(&config.TypeCheck, "typecheck", false,
 		"add type-checking theorems")
+	flag.StringVar(&config.FFI, "ffi", "disk",
+		"FFI to translate for: disk, filesys, none, or a qualified Coq module with a custom prelude")
 
 	var outFile string
 	flag.StringVar(&outFile, "out", "-",
@@ -33,18 +52,65 @@ func main() {
 	flag.StringVar(&packagePath, "package", "",
 		"output to a package path")
 
+	var multiplePackages bool
+	flag.BoolVar(&multiplePackages, "packages", false,
+		"translate all packages matching the arguments (package patterns, as in go build)\n"+
+			"into a directory given by -out, with one file per package")
+
+	var externalFile string
+	flag.StringVar(&externalFile, "external", "",
+		"JSON or YAML file declaring external packages and their translation")
+
 	var ignoreErrors bool
 	flag.BoolVar(&ignoreErrors, "ignore-errors", false,
-		"output partial translation even if there are errors")
+		"output partial translation even if there are errors, with axioms for\n"+
+			"the declarations that could not be translated")
 
 	flag.Parse()
+	config.PartialTranslation = ignoreErrors
+	red := color.New(color.FgRed).SprintFunc()
+
+	if externalFile != "" {
+		pkgs, err := goose.LoadExternalPackages(externalFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, red(err.Error()))
+			os.Exit(1)
+		}
+		config.ExternalPackages = pkgs
+	}
+
+	if multiplePackages {
+		if flag.NArg() == 0 || outFile == "-" || packagePath != "" {
+			flag.Usage()
+			os.Exit(1)
+		}
+		files, err := config.TranslatePackages("", flag.Args()...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, red(err.Error()))
+			if !ignoreErrors || files == nil {
+				os.Exit(1)
+			}
+		}
+		for _, f := range files {
+			err := writeFile(f, path.Join(outFile, coq.ImportToPath(f.GoPackage)))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				fmt.Fprintln(os.Stderr, red("could not write output"))
+				os.Exit(1)
+			}
+		}
+		if err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
 	if flag.NArg() != 1 {
 		flag.Usage()
 		os.Exit(1)
 	}
 	srcDir := flag.Arg(0)
 
-	red := color.New(color.FgRed).SprintFunc()
 	f, err := config.TranslatePackage(packagePath, srcDir)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, red(err.Error()))
@@ -57,20 +123,13 @@ func main() {
 	} else {
 		if packagePath != "" {
 			outFile = path.Join(outFile, coq.ImportToPath(packagePath))
-			outDir := path.Dir(outFile)
-			_, err := os.Stat(outDir)
-			if os.IsNotExist(err) {
-				os.MkdirAll(outDir, 0777)
-			}
 		}
-		out, err := os.Create(outFile)
+		err := writeFile(f, outFile)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err.Error())
 			fmt.Fprintln(os.Stderr, red("could not write output"))
 			os.Exit(1)
 		}
-		defer out.Close()
-		f.Write(out)
 	}
 	if err != nil {
 		os.Exit(1)
@@ -10,11 +10,15 @@ import (
 	"path"
 	"regexp"
 	"strings"
+
+	"github.com/tchajed/goose"
+	"github.com/tchajed/goose/internal/coq"
 )
 
+// coqHeader is the start of the generated Coq file, which uses the same
+// imports as goose for the FFI
 const coqHeader string = `(* autogenerated by goose/cmd/test_gen *)
-From Perennial.goose_lang Require Import prelude.
-From Perennial.goose_lang Require Import ffi.disk_prelude.
+%s
 From Perennial.goose_lang.interpreter Require Import test_config.
 
 (* test functions *)
@@ -62,6 +66,10 @@ func main() {
 	flag.StringVar(&outFile, "out", "-",
 		"file to output to (use '-' for stdout)")
 
+	var config goose.Config
+	flag.StringVar(&config.FFI, "ffi", "disk",
+		"FFI the tests are run with (disk, filesys, none, or a qualified Coq module)")
+
 	flag.Parse()
 
 	var t string
@@ -101,7 +109,11 @@ func main() {
 	}
 
 	if t == "coq" {
-		fmt.Fprintf(out, coqHeader)
+		if err := config.CheckFFI(); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		fmt.Fprintf(out, coqHeader, coq.ImportHeader(config.FFIModule()))
 
 		for _, file := range files {
 
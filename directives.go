@@ -0,0 +1,172 @@
+package goose
+
+import (
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"strings"
+
+	"github.com/tchajed/goose/internal/coq"
+)
+
+// Directives are comments of the form //goose:<directive> in the doc comment
+// of a top-level declaration, which mark trusted code that goose does not
+// (fully) translate.
+const (
+	// directiveSkip omits the declaration from the translation
+	directiveSkip = "skip"
+	// directiveAxiom declares a function as a Coq axiom rather than
+	// translating its body
+	directiveAxiom = "axiom"
+	// directiveOpaque translates a function but makes its definition opaque
+	directiveOpaque = "opaque"
+)
+
+const directivePrefix = "//goose:"
+
+// declDoc gives the doc comment of a top-level declaration
+func declDoc(d ast.Decl) *ast.CommentGroup {
+	switch d := d.(type) {
+	case *ast.FuncDecl:
+		return d.Doc
+	case *ast.GenDecl:
+		return d.Doc
+	}
+	return nil
+}
+
+// declDirectives gives the names of the goose directives on a declaration
+func declDirectives(d ast.Decl) []string {
+	doc := declDoc(d)
+	if doc == nil {
+		return nil
+	}
+	var directives []string
+	for _, c := range doc.List {
+		if !strings.HasPrefix(c.Text, directivePrefix) {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(c.Text, directivePrefix))
+		if len(fields) == 0 {
+			directives = append(directives, "")
+			continue
+		}
+		directives = append(directives, fields[0])
+	}
+	return directives
+}
+
+// directive gives the goose directive on a declaration, if any (ignoring
+// invalid directives, which checkDirective reports)
+func directive(d ast.Decl) string {
+	directives := declDirectives(d)
+	if len(directives) != 1 {
+		return ""
+	}
+	return directives[0]
+}
+
+// checkDirective checks that the directive on a declaration is valid and
+// applies to that kind of declaration
+func (ctx Ctx) checkDirective(d ast.Decl) string {
+	directives := declDirectives(d)
+	if len(directives) == 0 {
+		return ""
+	}
+	name := declName(d)
+	if name == nil {
+		ctx.unsupported(d, "goose directive on declaration without a name")
+	}
+	if len(directives) > 1 {
+		ctx.unsupported(name, "multiple goose directives on %s", name.Name)
+	}
+	switch dir := directives[0]; dir {
+	case directiveSkip:
+		return dir
+	case directiveAxiom, directiveOpaque:
+		if _, ok := d.(*ast.FuncDecl); !ok {
+			ctx.unsupported(name, "goose:%s only applies to functions", dir)
+		}
+		return dir
+	default:
+		ctx.unsupported(name, "unknown directive goose:%s", dir)
+	}
+	return ""
+}
+
+// funcAxiom declares a function as an axiom, translating only its signature
+func (ctx Ctx) funcAxiom(d *ast.FuncDecl) coq.AxiomDecl {
+	return coq.AxiomDecl{Func: ctx.funcSignature(d)}
+}
+
+// directiveSummary lists the declarations with goose directives, which are the
+// code whose translation is trusted, and separately those replaced by axioms
+// in a partial translation
+func (ctx Ctx) directiveSummary(units []declUnit) []coq.Decl {
+	var trusted, failed []string
+	for _, u := range units {
+		if u.decl == nil || u.name == nil {
+			continue
+		}
+		pos := ctx.fset.Position(u.decl.Pos())
+		where := fmt.Sprintf("%s (%s:%d)",
+			goDeclName(u.decl), filepath.Base(pos.Filename), pos.Line)
+		if u.failed {
+			failed = append(failed, where)
+		} else if dir := directive(u.decl); dir != "" {
+			trusted = append(trusted, "goose:"+dir+" "+where)
+		}
+	}
+	var decls []coq.Decl
+	if len(trusted) > 0 {
+		decls = append(decls, coq.NewComment("trusted declarations:\n"+
+			strings.Join(trusted, "\n")))
+	}
+	if len(failed) > 0 {
+		decls = append(decls, coq.NewComment("failed declarations:\n"+
+			strings.Join(failed, "\n")))
+	}
+	return decls
+}
+
+// recvTypeName gives the Go name of a method's receiver type, if it is a
+// (pointer to a) named type
+func recvTypeName(d *ast.FuncDecl) (string, bool) {
+	if d.Recv == nil || len(d.Recv.List) != 1 {
+		return "", false
+	}
+	recv := d.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	if ident, ok := recv.(*ast.Ident); ok {
+		return ident.Name, true
+	}
+	return "", false
+}
+
+// goDeclName gives the Go names defined by a declaration, including the
+// receiver type for methods and every name in a grouped declaration
+func goDeclName(d ast.Decl) string {
+	switch d := d.(type) {
+	case *ast.FuncDecl:
+		if recv, ok := recvTypeName(d); ok {
+			return recv + "." + d.Name.Name
+		}
+		return d.Name.Name
+	case *ast.GenDecl:
+		var names []string
+		for _, s := range d.Specs {
+			switch s := s.(type) {
+			case *ast.TypeSpec:
+				names = append(names, s.Name.Name)
+			case *ast.ValueSpec:
+				for _, name := range s.Names {
+					names = append(names, name.Name)
+				}
+			}
+		}
+		return strings.Join(names, ", ")
+	}
+	return declName(d).Name
+}
@@ -106,22 +106,26 @@ func (e *ConversionError) Error() string {
 	return strings.Join(lines, "\n")
 }
 
-func (r errorReporter) prefixed(prefix string, n ast.Node, msg string, args ...interface{}) {
+// newError builds a ConversionError for n; skip is the number of reporting
+// methods between the caller of newError and the goose code to blame
+func (r errorReporter) newError(skip int, prefix string, n ast.Node, msg string, args ...interface{}) *ConversionError {
 	where := r.fset.Position(n.Pos())
 	what := r.printGo(n)
 	formatted := fmt.Sprintf(msg, args...)
 
-	err := &ConversionError{
+	return &ConversionError{
 		Category:    prefix,
 		Message:     formatted,
 		GoCode:      what,
-		GooseCaller: getCaller(2),
+		GooseCaller: getCaller(2 + skip),
 		GoSrcFile:   where.String(),
 		Pos:         n.Pos(),
 		End:         n.End(),
 	}
+}
 
-	panic(gooseError{err: err})
+func (r errorReporter) prefixed(prefix string, n ast.Node, msg string, args ...interface{}) {
+	panic(gooseError{err: r.newError(1, prefix, n, msg, args...)})
 }
 
 // nope reports a situation that I thought was impossible from reading the
@@ -153,3 +157,9 @@ func (r errorReporter) todo(n ast.Node, msg string, args ...interface{}) {
 func (r errorReporter) unsupported(n ast.Node, msg string, args ...interface{}) {
 	r.prefixed("unsupported", n, msg, args...)
 }
+
+// unsupportedError is like unsupported, but returns the error rather than
+// reporting it, for checks that collect several errors
+func (r errorReporter) unsupportedError(n ast.Node, msg string, args ...interface{}) error {
+	return r.newError(0, "unsupported", n, msg, args...)
+}
@@ -28,6 +28,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/tchajed/goose"
+	"github.com/tchajed/goose/internal/coq"
 )
 
 var updateGold = flag.Bool("update-gold",
@@ -128,7 +129,12 @@ func testExample(testingT *testing.T, name string, conf goose.Config) {
 		fmt.Fprintln(os.Stderr, terr)
 		assert.FailNow("translation failed")
 	}
+	t.checkGold(assert, f)
+}
 
+// checkGold compares a translation to the test's gold output (or updates the
+// gold output with -update-gold)
+func (t positiveTest) checkGold(assert *assert.Assertions, f coq.File) {
 	var b bytes.Buffer
 	f.Write(&b)
 	actual := b.String()
@@ -165,7 +171,7 @@ func TestUnitTests(t *testing.T) {
 }
 
 func TestSimpleDb(t *testing.T) {
-	testExample(t, "simpledb", goose.Config{})
+	testExample(t, "simpledb", goose.Config{FFI: "filesys"})
 }
 
 func TestWal(t *testing.T) {
@@ -188,6 +194,139 @@ func TestSemantics(t *testing.T) {
 	testExample(t, "semantics", goose.Config{TypeCheck: true})
 }
 
+func TestShared(t *testing.T) {
+	testExample(t, "shared", goose.Config{})
+}
+
+func TestImporting(t *testing.T) {
+	testExample(t, "importing", goose.Config{})
+}
+
+// TestExternalNoopResult checks that a noop external function, which
+// translates to a comment, cannot be used for its value
+func TestExternalNoopResult(t *testing.T) {
+	pkgs, err := goose.LoadExternalPackages(
+		"internal/examples/external/external.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgs[0].Functions["RoundUp"] = goose.ExternalFunc{Noop: true}
+	_, err = goose.Config{ExternalPackages: pkgs}.
+		TranslatePackage("", "internal/examples/external")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "noop external function trusted.RoundUp returns a value")
+}
+
+func TestExternalMethodValueDrop(t *testing.T) {
+	pkgs, err := goose.LoadExternalPackages(
+		"internal/examples/external/external.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgs[0].Functions["Buf.Len"] = goose.ExternalFunc{Coq: "buf.len", Drop: []int{0}}
+	_, err = goose.Config{ExternalPackages: pkgs}.
+		TranslatePackage("", "internal/examples/external")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "external function w.buf.Len used as a value")
+	assert.Contains(t, err.Error(), "external function (*trusted.Buf).Len used as a value")
+}
+
+func TestFFIMismatch(t *testing.T) {
+	_, err := goose.Config{}.TranslatePackage("", "internal/examples/simpledb")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "requires the filesys FFI")
+}
+
+func TestCustomFFI(t *testing.T) {
+	assert := assert.New(t)
+	f, err := goose.Config{FFI: "Flash.flash_prelude"}.TranslatePackage("",
+		"internal/examples/simpledb")
+	assert.NoError(err)
+	assert.Equal("Flash.flash_prelude", f.FFI)
+
+	for _, ffi := range []string{"dsk", "flash prelude", "Flash."} {
+		_, err = goose.Config{FFI: ffi}.TranslatePackage("",
+			"internal/examples/unittest")
+		if assert.Error(err) {
+			assert.Contains(err.Error(), fmt.Sprintf("invalid FFI %q", ffi))
+		}
+	}
+}
+
+// TestPartialTranslation checks that declarations that fail to translate are
+// replaced with axioms, while still reporting the errors
+func TestPartialTranslation(testingT *testing.T) {
+	assert := assert.New(testingT)
+	t := positiveTest{newTest("internal/examples", "partial")}
+	f, err := goose.Config{PartialTranslation: true, TypeCheck: true}.
+		TranslatePackage("", t.path)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "5 errors")
+	}
+	t.checkGold(assert, f)
+}
+
+func TestExternal(t *testing.T) {
+	pkgs, err := goose.LoadExternalPackages(
+		"internal/examples/external/external.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testExample(t, "external", goose.Config{ExternalPackages: pkgs})
+}
+
+// TestTranslatePackages checks that loading packages with go/packages gives
+// the same translation as translating each package directory
+func TestTranslatePackages(testingT *testing.T) {
+	assert := assert.New(testingT)
+	files, err := goose.Config{}.TranslatePackages(".",
+		"./internal/examples/unittest",
+		"./internal/examples/importing", "./internal/examples/shared")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		assert.FailNow("translation failed")
+	}
+	assert.Len(files, 3)
+	var order []string
+	for _, f := range files {
+		order = append(order, path.Base(f.GoPackage))
+	}
+	assert.Less(indexOf(order, "shared"), indexOf(order, "importing"),
+		"dependencies should be translated first")
+	// simpledb uses machine/filesys, so it is translated for that FFI
+	fsFiles, err := goose.Config{FFI: "filesys"}.TranslatePackages(".",
+		"./internal/examples/simpledb")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		assert.FailNow("translation failed")
+	}
+	assert.Len(fsFiles, 1)
+	files = append(files, fsFiles...)
+	for _, f := range files {
+		name := path.Base(f.GoPackage)
+		assert.Equal("github.com/tchajed/goose/internal/examples/"+name,
+			f.GoPackage)
+		var b bytes.Buffer
+		f.Write(&b)
+		t := positiveTest{newTest("internal/examples", name)}
+		// skip the first line, which names the package
+		skipHeader := func(s string) string {
+			return s[strings.Index(s, "\n"):]
+		}
+		assert.Equal(skipHeader(t.Gold()), skipHeader(b.String()),
+			"translation of %s", f.GoPackage)
+	}
+}
+
+func indexOf(xs []string, x string) int {
+	for i, y := range xs {
+		if y == x {
+			return i
+		}
+	}
+	return -1
+}
+
 type errorExpectation struct {
 	Line  int
 	Error string
@@ -225,7 +364,9 @@ func getExpectedError(fset *token.FileSet,
 func translateErrorFile(assert *assert.Assertions, filePath string) *errorTestResult {
 	fset := token.NewFileSet()
 	pkgName := "example"
-	ctx := goose.NewCtx(pkgName, fset, goose.Config{})
+	// translate for the filesys FFI so that negative examples can use either
+	// machine/filesys or (to test FFI checking) machine/disk
+	ctx := goose.NewCtx(pkgName, fset, goose.Config{FFI: "filesys"})
 	f, err := parser.ParseFile(fset, filePath, nil, parser.ParseComments)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -0,0 +1,218 @@
+package goose
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/types"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tchajed/goose/internal/coq"
+)
+
+// An ExternalPackage declares how to translate a Go package that goose does
+// not translate itself, such as a trusted library implemented directly in
+// GooseLang.
+//
+// The Coq definitions referred to must be available to the translated code,
+// for example from the FFI prelude.
+type ExternalPackage struct {
+	// Path is the import path of the Go package
+	Path string `json:"path" yaml:"path"`
+	// Functions gives the translation of the package's functions, by name,
+	// and methods, as Type.Method
+	Functions map[string]ExternalFunc `json:"functions" yaml:"functions"`
+	// Types gives the GooseLang type for each of the package's types, by name,
+	// and optionally for pointers to them, as *Type (otherwise a pointer is
+	// translated as usual, as a reference to the type)
+	Types map[string]string `json:"types" yaml:"types"`
+}
+
+// ExternalFunc is the translation of a function from an ExternalPackage
+type ExternalFunc struct {
+	// Coq is the GooseLang function that calls translate to
+	Coq string `json:"coq" yaml:"coq"`
+	// Drop lists the (zero-based) indices of arguments to leave out of the
+	// call, where a method's receiver is argument 0
+	Drop []int `json:"drop" yaml:"drop"`
+	// Noop translates calls to a comment, as for log.Printf (the function
+	// must not return anything)
+	Noop bool `json:"noop" yaml:"noop"`
+}
+
+// LoadExternalPackages reads a list of external package declarations from a
+// JSON or (if the file name ends in .yaml or .yml) YAML file.
+func LoadExternalPackages(file string) ([]ExternalPackage, error) {
+	contents, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var pkgs []ExternalPackage
+	switch filepath.Ext(file) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(contents, &pkgs)
+	default:
+		err = json.Unmarshal(contents, &pkgs)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse %s", file)
+	}
+	for _, pkg := range pkgs {
+		if pkg.Path == "" {
+			return nil, errors.Errorf("%s: external package without a path", file)
+		}
+	}
+	return pkgs, nil
+}
+
+// externalPackage finds the declaration for an external package by import
+// path
+func (config Config) externalPackage(path string) (ExternalPackage, bool) {
+	for _, pkg := range config.ExternalPackages {
+		if pkg.Path == path {
+			return pkg, true
+		}
+	}
+	return ExternalPackage{}, false
+}
+
+// isBuiltinImport checks if a package is translated by goose itself (as
+// opposed to referring to its own translation)
+func (ctx Ctx) isBuiltinImport(path string) bool {
+	if _, ok := ctx.externalPackage(path); ok {
+		return true
+	}
+	return builtinImports[path]
+}
+
+// externalFunc finds the translation of a function or method (given as
+// Type.Method) from an external package
+func (ctx Ctx) externalFunc(n ast.Node, pkg ExternalPackage, name string) ExternalFunc {
+	f, ok := pkg.Functions[name]
+	if !ok {
+		ctx.unsupported(n, "%s is not declared in external package %s",
+			name, pkg.Path)
+	}
+	if f.Coq == "" && !f.Noop {
+		ctx.unsupported(n, "external function %s has no translation", name)
+	}
+	return f
+}
+
+// externalType gives the declared GooseLang type for a named type from an
+// external package
+func (ctx Ctx) externalType(n ast.Node, t *types.Named) (coq.Type, bool) {
+	pkg, ok := ctx.externalPackage(t.Obj().Pkg().Path())
+	if !ok {
+		return nil, false
+	}
+	ty, ok := pkg.Types[t.Obj().Name()]
+	if !ok {
+		ctx.unsupported(n, "type %s is not declared in external package %s",
+			t.Obj().Name(), pkg.Path)
+	}
+	return coq.TypeIdent(ty), true
+}
+
+// externalPtrType gives the declared GooseLang type for a pointer to a named
+// type from an external package, if there is one (as *Type)
+func (ctx Ctx) externalPtrType(t types.Type) (coq.Type, bool) {
+	pt, ok := t.(*types.Pointer)
+	if !ok {
+		return nil, false
+	}
+	named, ok := pt.Elem().(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return nil, false
+	}
+	pkg, ok := ctx.externalPackage(named.Obj().Pkg().Path())
+	if !ok {
+		return nil, false
+	}
+	ty, ok := pkg.Types["*"+named.Obj().Name()]
+	return coq.TypeIdent(ty), ok
+}
+
+// externalCall translates a call to an external function, given the
+// translated arguments (including the receiver for a method)
+func (ctx Ctx) externalCall(call *ast.CallExpr, f ExternalFunc, args []coq.Expr) coq.Expr {
+	if f.Noop {
+		// the call becomes a comment, which has no value
+		if sig, ok := ctx.typeOf(call.Fun).(*types.Signature); ok &&
+			sig.Results().Len() > 0 {
+			ctx.unsupported(call, "noop external function %s returns a value",
+				ctx.printGo(call.Fun))
+		}
+		return coq.LoggingStmt{GoCall: ctx.printGo(call)}
+	}
+	dropped := make(map[int]bool)
+	for _, i := range f.Drop {
+		dropped[i] = true
+	}
+	var keptArgs []coq.Expr
+	for i, arg := range args {
+		if !dropped[i] {
+			keptArgs = append(keptArgs, arg)
+		}
+	}
+	return coq.NewCallExpr(f.Coq, keptArgs...)
+}
+
+// externalMethodFunc finds the translation of a method sel from an external
+// package, if its receiver type is from one
+func (ctx Ctx) externalMethodFunc(n ast.Node, sel *types.Selection) (ExternalFunc, bool) {
+	recv := sel.Obj().Type().(*types.Signature).Recv().Type()
+	recvType, ok := namedReceiver(recv)
+	if !ok || recvType.Obj().Pkg() == nil {
+		return ExternalFunc{}, false
+	}
+	pkg, ok := ctx.externalPackage(recvType.Obj().Pkg().Path())
+	if !ok {
+		return ExternalFunc{}, false
+	}
+	name := recvType.Obj().Name() + "." + sel.Obj().Name()
+	return ctx.externalFunc(n, pkg, name), true
+}
+
+// externalMethod translates a method call on a value whose type is from an
+// external package
+func (ctx Ctx) externalMethod(f *ast.SelectorExpr, call *ast.CallExpr) (coq.Expr, bool) {
+	sel, ok := ctx.info.Selections[f]
+	if !ok {
+		return nil, false
+	}
+	method, ok := ctx.externalMethodFunc(f, sel)
+	if !ok {
+		return nil, false
+	}
+	var args []coq.Expr
+	if sel.Kind() == types.MethodVal {
+		args = append(args, ctx.expr(f.X))
+	}
+	// a method expression passes the receiver as the first argument
+	args = append(args, ctx.callArgs(call)...)
+	return ctx.externalCall(call, method, args), true
+}
+
+// externalFuncValue translates a reference to a function from an external
+// package other than a direct call
+func (ctx Ctx) externalFuncValue(e ast.Expr, pkg ExternalPackage, obj types.Object) coq.Expr {
+	if _, ok := obj.(*types.Func); !ok {
+		ctx.unsupported(e, "%s from external package %s is not a function",
+			obj.Name(), pkg.Path)
+	}
+	return coq.GallinaIdent(ctx.externalValue(e, ctx.externalFunc(e, pkg, obj.Name())))
+}
+
+// externalValue gives the GooseLang function for an external function or
+// method used other than in a direct call, which must be translated without
+// changing its arguments
+func (ctx Ctx) externalValue(e ast.Expr, f ExternalFunc) string {
+	if f.Noop || len(f.Drop) > 0 {
+		ctx.unsupported(e, "external function %s used as a value", ctx.printGo(e))
+	}
+	return f.Coq
+}
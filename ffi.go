@@ -0,0 +1,76 @@
+package goose
+
+import (
+	"go/ast"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// ffiModules gives the Coq module with the GooseLang prelude for each of the
+// FFIs goose supports (none has no module)
+var ffiModules = map[string]string{
+	"disk":    "Perennial.goose_lang.ffi.disk_prelude",
+	"filesys": "Perennial.goose_lang.ffi.filesys_prelude",
+	"none":    "",
+}
+
+// ffiPackages gives the FFI required by each of the machine/* packages that
+// need one (the machine package itself only needs the prelude)
+var ffiPackages = map[string]string{
+	diskPkg:    "disk",
+	filesysPkg: "filesys",
+}
+
+// ffi gives the name of the FFI being translated for
+func (config Config) ffi() string {
+	if config.FFI == "" {
+		return "disk"
+	}
+	return config.FFI
+}
+
+// isCustomFFI checks if the FFI is a user-provided Coq module, rather than
+// one of the FFIs goose knows about
+func (config Config) isCustomFFI() bool {
+	_, ok := ffiModules[config.ffi()]
+	return !ok
+}
+
+// coqModulePath matches a qualified Coq module name (a bare name is more
+// likely a misspelling of one of the built-in FFIs)
+var coqModulePath = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_']*(\.[A-Za-z_][A-Za-z0-9_']*)+$`)
+
+// CheckFFI checks that the FFI is either one goose knows about or a qualified
+// Coq module path
+func (config Config) CheckFFI() error {
+	if config.isCustomFFI() && !coqModulePath.MatchString(config.FFI) {
+		return errors.Errorf("invalid FFI %q "+
+			"(expected disk, filesys, none, or a qualified Coq module path)", config.FFI)
+	}
+	return nil
+}
+
+// FFIModule gives the Coq module that translated code imports for its FFI,
+// or the empty string if the translation uses no FFI
+func (config Config) FFIModule() string {
+	if config.isCustomFFI() {
+		return config.FFI
+	}
+	return ffiModules[config.ffi()]
+}
+
+// checkImportFFI reports an import of a machine/* package that requires a
+// different FFI than the one being translated for
+//
+// A custom FFI might support anything, so any import is allowed.
+func (ctx Ctx) checkImportFFI(s *ast.ImportSpec, importPath string) {
+	ffi, ok := ffiPackages[importPath]
+	if !ok || ctx.isCustomFFI() {
+		return
+	}
+	if ffi != ctx.ffi() {
+		ctx.unsupported(s, "%s requires the %s FFI (translating for %s)",
+			importPath, ffi, ctx.ffi())
+	}
+}
@@ -23,6 +23,8 @@ import (
 	"strings"
 	"unicode"
 
+	"golang.org/x/tools/go/packages"
+
 	"github.com/tchajed/goose/internal/coq"
 )
 
@@ -112,6 +114,9 @@ type Ctx struct {
 	info    *types.Info
 	fset    *token.FileSet
 	pkgPath string
+	// results of the function being translated, which give a type to an
+	// untyped nil in a return statement
+	results *types.Tuple
 	errorReporter
 	Config
 }
@@ -120,15 +125,27 @@ type Ctx struct {
 type Config struct {
 	AddSourceFileComments bool
 	TypeCheck             bool
+	// ExternalPackages declares additional packages that goose translates
+	// directly, rather than referring to their translation
+	ExternalPackages []ExternalPackage
+	// FFI is the external interface the code is translated for: disk (the
+	// default), filesys, none, or the qualified path of a Coq module with a
+	// custom prelude
+	FFI string
+	// PartialTranslation replaces declarations that fail to translate with
+	// axioms (the errors are still reported), so the rest of the output can
+	// be checked
+	PartialTranslation bool
 }
 
 // NewCtx initializes a context
 func NewCtx(pkgPath string, fset *token.FileSet, config Config) Ctx {
 	info := &types.Info{
-		Defs:   make(map[*ast.Ident]types.Object),
-		Uses:   make(map[*ast.Ident]types.Object),
-		Types:  make(map[ast.Expr]types.TypeAndValue),
-		Scopes: make(map[ast.Node]*types.Scope),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Scopes:     make(map[ast.Node]*types.Scope),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
 	}
 	return Ctx{
 		idents:        newIdentCtx(),
@@ -140,6 +157,19 @@ func NewCtx(pkgPath string, fset *token.FileSet, config Config) Ctx {
 	}
 }
 
+// NewPkgCtx initializes a context for a package loaded with go/packages,
+// which has already been type-checked
+func NewPkgCtx(pkg *packages.Package, config Config) Ctx {
+	return Ctx{
+		idents:        newIdentCtx(),
+		info:          pkg.TypesInfo,
+		fset:          pkg.Fset,
+		pkgPath:       pkg.PkgPath,
+		errorReporter: newErrorReporter(pkg.Fset),
+		Config:        config,
+	}
+}
+
 // TypeCheck type-checks a set of files and stores the result in the Ctx
 //
 // This is needed before conversion to Coq to disambiguate some methods.
@@ -185,6 +215,39 @@ func isIdent(e ast.Expr, ident string) bool {
 	return ok && i == ident
 }
 
+// import paths of the packages goose has special support for
+const (
+	machinePkg = "github.com/tchajed/goose/machine"
+	diskPkg    = "github.com/tchajed/goose/machine/disk"
+	filesysPkg = "github.com/tchajed/goose/machine/filesys"
+)
+
+// importedPkg gives the import path of the package e refers to, if e is an
+// imported package name (under whatever name it was imported)
+func (ctx Ctx) importedPkg(e ast.Expr) (path string, ok bool) {
+	ident, ok := e.(*ast.Ident)
+	if !ok {
+		return "", false
+	}
+	pkg, ok := ctx.info.Uses[ident].(*types.PkgName)
+	if !ok {
+		return "", false
+	}
+	return pkg.Imported().Path(), true
+}
+
+// isPkg checks if e refers to the imported package with the given path
+func (ctx Ctx) isPkg(e ast.Expr, path string) bool {
+	p, ok := ctx.importedPkg(e)
+	return ok && p == path
+}
+
+// isPkgObject checks if obj is the object name from the package with the
+// given path
+func isPkgObject(obj types.Object, path string, name string) bool {
+	return obj.Pkg() != nil && obj.Pkg().Path() == path && obj.Name() == name
+}
+
 func (ctx Ctx) mapType(e *ast.MapType) coq.MapType {
 	ty := ctx.typeOf(e).Underlying().(*types.Map)
 	info, ok := getIntegerType(ty.Key())
@@ -196,15 +259,16 @@ func (ctx Ctx) mapType(e *ast.MapType) coq.MapType {
 }
 
 func (ctx Ctx) selectorExprType(e *ast.SelectorExpr) coq.Expr {
-	if isIdent(e.X, "filesys") && isIdent(e.Sel, "File") {
+	if ctx.isPkg(e.X, filesysPkg) && e.Sel.Name == "File" {
 		return coq.TypeIdent("fileT")
 	}
-	if isIdent(e.X, "disk") && isIdent(e.Sel, "Block") {
+	if ctx.isPkg(e.X, diskPkg) && e.Sel.Name == "Block" {
 		return coq.TypeIdent("disk.blockT")
 	}
-	if isIdent(e.X, "sync") &&
-		(isIdent(e.Sel, "Cond") || isIdent(e.Sel, "Mutex")) {
-		ctx.unsupported(e, "%s without pointer indirection", ctx.printGo(e))
+	if alias, ok := ctx.typeOf(e).(*types.Alias); ok &&
+		!ctx.isBuiltinImport(alias.Obj().Pkg().Path()) {
+		// refer to the alias's own definition, as for a local alias
+		return coq.TypeIdent(ctx.qualifiedName(alias.Obj()))
 	}
 	return ctx.coqTypeOfType(e, ctx.typeOf(e))
 }
@@ -220,14 +284,27 @@ func (ctx Ctx) coqTypeOfType(n ast.Node, t types.Type) coq.Type {
 	// syntactic type and we need to operate on the output of type inference
 	// anyway.
 	switch t := t.(type) {
+	case *types.Alias:
+		// go/types represents aliases explicitly (including any, which is an
+		// alias for interface{}); they translate to the type they stand for
+		return ctx.coqTypeOfType(n, types.Unalias(t))
 	case *types.Struct:
 		ctx.unsupported(n, "type for anonymous struct")
+	case *types.Interface:
+		if t.Empty() {
+			return coq.TypeIdent("anyT")
+		}
+		ctx.unsupported(n, "non-empty interface")
 	case *types.Basic:
 		switch t.Name() {
 		case "uint64":
 			return coq.TypeIdent("uint64T")
 		case "uint32":
 			return coq.TypeIdent("uint32T")
+		case "rune", "untyped rune":
+			// runes are represented by their (non-negative) code point; other
+			// int32s are signed, and unsupported
+			return coq.TypeIdent("uint32T")
 		case "byte":
 			return coq.TypeIdent("byteT")
 		case "bool":
@@ -238,31 +315,76 @@ func (ctx Ctx) coqTypeOfType(n ast.Node, t types.Type) coq.Type {
 			ctx.unsupported(n, "basic type %s", t.Name())
 		}
 	case *types.Pointer:
-		if isLockRef(t) {
-			return coq.TypeIdent("lockRefT")
+		if name, ok := syncRefType(t); ok {
+			return coq.TypeIdent(syncRefTypes[name])
+		}
+		if ty, ok := ctx.externalPtrType(t); ok {
+			return ty
 		}
 		return coq.PtrType{ctx.coqTypeOfType(n, t.Elem())}
 	case *types.Named:
-		if t.Obj().Pkg().Name() == "filesys" && t.Obj().Name() == "File" {
+		if isErrorType(t) {
+			return coq.TypeIdent("errorT")
+		}
+		if isPkgObject(t.Obj(), filesysPkg, "File") {
 			return coq.TypeIdent("fileT")
 		}
-		if t.Obj().Pkg().Name() == "disk" && t.Obj().Name() == "Disk" {
+		if isPkgObject(t.Obj(), diskPkg, "Disk") {
 			return coq.TypeIdent("disk.Disk")
 		}
+		if name, ok := syncValueType(t); ok {
+			return coq.TypeIdent(syncValueTypes[name])
+		}
+		if name, ok := atomicType(t); ok {
+			return coq.TypeIdent(atomicTypes[name])
+		}
+		if ty, ok := ctx.externalType(n, t); ok {
+			return ty
+		}
 		if info, ok := ctx.getStructInfo(t); ok {
 			return coq.StructName(info.name)
 		}
 		return coq.TypeIdent(ctx.qualifiedName(t.Obj()))
 	case *types.Slice:
 		return coq.SliceType{ctx.coqTypeOfType(n, t.Elem())}
+	case *types.Array:
+		return coq.ArrayType{
+			Len: uint64(t.Len()),
+			Elt: ctx.coqTypeOfType(n, t.Elem()),
+		}
 	case *types.Map:
 		return coq.MapType{ctx.coqTypeOfType(n, t.Elem())}
+	case *types.Signature:
+		return ctx.funcType(n, t)
 	}
 	panic(fmt.Errorf("unhandled type %v", t))
 }
 
+// funcType translates the type of a function value (which is curried, like the
+// functions goose generates)
+func (ctx Ctx) funcType(n ast.Node, sig *types.Signature) coq.Type {
+	var results []coq.Type
+	for i := 0; i < sig.Results().Len(); i++ {
+		results = append(results, ctx.coqTypeOfType(n, sig.Results().At(i).Type()))
+	}
+	var ty coq.Type = coq.TypeIdent("unitT")
+	if len(results) > 0 {
+		ty = coq.NewTupleType(results)
+	}
+	if sig.Params().Len() == 0 {
+		return coq.ArrowType{Arg: coq.TypeIdent("unitT"), Ret: ty}
+	}
+	for i := sig.Params().Len() - 1; i >= 0; i-- {
+		ty = coq.ArrowType{
+			Arg: ctx.coqTypeOfType(n, sig.Params().At(i).Type()),
+			Ret: ty,
+		}
+	}
+	return ty
+}
+
 func sliceElem(t types.Type) types.Type {
-	if t, ok := t.(*types.Slice); ok {
+	if t, ok := t.Underlying().(*types.Slice); ok {
 		return t.Elem()
 	}
 	panic(fmt.Errorf("expected slice type, got %v", t))
@@ -284,14 +406,12 @@ func (ctx Ctx) arrayType(e *ast.ArrayType) coq.Type {
 }
 
 func (ctx Ctx) ptrType(e *ast.StarExpr) coq.Type {
-	// check for *sync.Mutex
-	if e, ok := e.X.(*ast.SelectorExpr); ok {
-		if isIdent(e.X, "sync") && isIdent(e.Sel, "Mutex") {
-			return coq.TypeIdent("lockRefT")
-		}
-		if isIdent(e.X, "sync") && isIdent(e.Sel, "Cond") {
-			return coq.TypeIdent("condvarRefT")
-		}
+	// check for *sync.Mutex and other synchronization primitives
+	if name, ok := syncRefType(ctx.typeOf(e)); ok {
+		return coq.TypeIdent(syncRefTypes[name])
+	}
+	if ty, ok := ctx.externalPtrType(ctx.typeOf(e)); ok {
+		return ty
 	}
 	info, ok := ctx.getStructInfo(ctx.typeOf(e.X))
 	if ok {
@@ -325,10 +445,10 @@ func (ctx Ctx) coqType(e ast.Expr) coq.Type {
 		} else {
 			ctx.unsupported(e, "non-empty interface")
 		}
+	case *ast.FuncType:
+		return ctx.coqTypeOfType(e, ctx.typeOf(e))
 	case *ast.Ellipsis:
-		// NOTE: ellipsis types are not fully supported
-		// we emit the right type here but Goose doesn't know how to call a method
-		// which takes variadic parameters (it'll pass them as separate arguments)
+		// a variadic parameter is a slice (callers pack the arguments)
 		return coq.SliceType{ctx.coqType(e.Elt)}
 	default:
 		ctx.unsupported(e, "unexpected type expr")
@@ -417,10 +537,6 @@ func (ctx Ctx) addSourceFile(node ast.Node, comment *string) {
 func (ctx Ctx) typeDecl(doc *ast.CommentGroup, spec *ast.TypeSpec) coq.Decl {
 	switch goTy := spec.Type.(type) {
 	case *ast.StructType:
-		ctx.addDef(spec.Name, identInfo{
-			IsPtrWrapped: false,
-			IsMacro:      false,
-		})
 		ty := coq.StructDecl{
 			Name: spec.Name.Name,
 		}
@@ -429,10 +545,6 @@ func (ctx Ctx) typeDecl(doc *ast.CommentGroup, spec *ast.TypeSpec) coq.Decl {
 		ty.Fields = ctx.structFields(spec.Name.Name, goTy.Fields)
 		return ty
 	default:
-		ctx.addDef(spec.Name, identInfo{
-			IsPtrWrapped: false,
-			IsMacro:      true,
-		})
 		return coq.TypeDecl{
 			Name: spec.Name.Name,
 			Body: ctx.coqType(spec.Type),
@@ -452,9 +564,13 @@ func toInitialLower(s string) string {
 	}, s)
 }
 
-func (ctx Ctx) lenExpr(e *ast.CallExpr) coq.CallExpr {
+func (ctx Ctx) lenExpr(e *ast.CallExpr) coq.Expr {
 	x := e.Args[0]
 	xTy := ctx.typeOf(x)
+	if ty, ok := arrayOrPtrToArray(xTy); ok {
+		// the length of an array is part of its type
+		return coq.IntLiteral{Value: uint64(ty.Len())}
+	}
 	switch ty := xTy.Underlying().(type) {
 	case *types.Slice:
 		return coq.NewCallExpr("slice.len", ctx.expr(x))
@@ -469,26 +585,109 @@ func (ctx Ctx) lenExpr(e *ast.CallExpr) coq.CallExpr {
 	return coq.CallExpr{}
 }
 
-func isLockRef(t types.Type) bool {
+// arrayOrPtrToArray returns the array type for t if t is an array or a pointer to
+// one (which Go allows to be indexed, sliced and measured like the array).
+func arrayOrPtrToArray(t types.Type) (ty *types.Array, ok bool) {
+	if pt, ok := t.Underlying().(*types.Pointer); ok {
+		t = pt.Elem()
+	}
+	ty, ok = t.Underlying().(*types.Array)
+	return
+}
+
+// syncRefTypes maps the sync types goose supports to the GooseLang types of
+// pointers to them
+var syncRefTypes = map[string]string{
+	"Mutex":     "lockRefT",
+	"Cond":      "condvarRefT",
+	"RWMutex":   "rwlockRefT",
+	"WaitGroup": "waitgroupRefT",
+	"Once":      "onceRefT",
+}
+
+// syncRefType returns the name of the sync type that t points to, if it is
+// one of the supported synchronization primitives
+func syncRefType(t types.Type) (name string, ok bool) {
 	if t, ok := t.(*types.Pointer); ok {
 		if t, ok := t.Elem().(*types.Named); ok {
-			name := t.Obj()
-			return name.Pkg().Name() == "sync" &&
-				name.Name() == "Mutex"
+			obj := t.Obj()
+			if obj.Pkg() != nil && obj.Pkg().Path() == "sync" {
+				_, ok := syncRefTypes[obj.Name()]
+				return obj.Name(), ok
+			}
 		}
 	}
-	return false
+	return "", false
 }
 
-func isCondVar(t types.Type) bool {
-	if t, ok := t.(*types.Pointer); ok {
-		if t, ok := t.Elem().(*types.Named); ok {
-			name := t.Obj()
-			return name.Pkg().Name() == "sync" &&
-				name.Name() == "Cond"
+// syncValueTypes gives the GooseLang types for sync types stored by value
+// (for example, as a struct field), which are allocated in place
+var syncValueTypes = map[string]string{
+	"Mutex":     "lockT",
+	"Cond":      "condvarT",
+	"RWMutex":   "rwlockT",
+	"WaitGroup": "waitgroupT",
+	"Once":      "onceT",
+}
+
+// syncValueType returns the name of t if it is a supported sync type (not a
+// pointer to one)
+func syncValueType(t types.Type) (name string, ok bool) {
+	return syncRefType(types.NewPointer(t))
+}
+
+// syncAddr translates an addressable sync value to its address, which is
+// what the library operations use
+func (ctx Ctx) syncAddr(e ast.Expr) coq.Expr {
+	if ident, ok := e.(*ast.Ident); ok && !ctx.identInfo(ident).IsPtrWrapped {
+		ctx.unsupported(e, "%s is not addressable\n\t(declare it with 'var' or use a pointer)", ident.Name)
+	}
+	return ctx.refExpr(e)
+}
+
+// atomicTypes gives the GooseLang types for the sync/atomic integer types,
+// which are stored in place like the integers they wrap
+var atomicTypes = map[string]string{
+	"Uint64": "uint64T",
+	"Uint32": "uint32T",
+}
+
+// atomicType returns the name of t if it is a supported sync/atomic type
+func atomicType(t types.Type) (name string, ok bool) {
+	if t, ok := t.(*types.Named); ok {
+		obj := t.Obj()
+		if obj.Pkg() != nil && obj.Pkg().Path() == "sync/atomic" {
+			_, ok := atomicTypes[obj.Name()]
+			return obj.Name(), ok
 		}
 	}
-	return false
+	return "", false
+}
+
+// atomicOps are the operations supported on atomic integers, both as
+// functions (atomic.AddUint64) and as methods (atomic.Uint64.Add)
+var atomicOps = map[string]bool{
+	"Load":           true,
+	"Store":          true,
+	"Add":            true,
+	"Swap":           true,
+	"CompareAndSwap": true,
+}
+
+// atomicFunc returns the GooseLang operation for a sync/atomic function such
+// as AddUint64, if it is supported
+func atomicFunc(name string) (string, bool) {
+	for ty := range atomicTypes {
+		if op := strings.TrimSuffix(name, ty); op != name && atomicOps[op] {
+			return "atomic." + name, true
+		}
+	}
+	return "", false
+}
+
+// isErrorType returns true if t is the built-in error type
+func isErrorType(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
 }
 
 func isByteSlice(t types.Type) bool {
@@ -507,44 +706,97 @@ func isString(t types.Type) bool {
 	return false
 }
 
-func (ctx Ctx) lockMethod(f *ast.SelectorExpr) coq.CallExpr {
-	l := ctx.expr(f.X)
-	switch f.Sel.Name {
-	case "Lock":
-		return coq.NewCallExpr("lock.acquire", l)
-	case "Unlock":
-		return coq.NewCallExpr("lock.release", l)
-	default:
-		ctx.nope(f, "method %s of sync.Mutex", ctx.printGo(f))
-		return coq.CallExpr{}
+// syncMethods maps methods of sync types to GooseLang library calls
+var syncMethods = map[string]map[string]string{
+	"Mutex": {
+		"Lock":   "lock.acquire",
+		"Unlock": "lock.release",
+	},
+	"Cond": {
+		"Signal":    "lock.condSignal",
+		"Broadcast": "lock.condBroadcast",
+		"Wait":      "lock.condWait",
+	},
+	"RWMutex": {
+		"Lock":    "rwlock.write_acquire",
+		"Unlock":  "rwlock.write_release",
+		"RLock":   "rwlock.read_acquire",
+		"RUnlock": "rwlock.read_release",
+	},
+	"WaitGroup": {
+		"Add":  "waitgroup.add",
+		"Done": "waitgroup.done",
+		"Wait": "waitgroup.wait",
+	},
+	"Once": {
+		"Do": "once.do",
+	},
+}
+
+// syncMethodFunc returns the library function for a method of a sync or
+// sync/atomic type, along with a reference l to the receiver, which the
+// library functions take as their first argument
+func (ctx Ctx) syncMethodFunc(f *ast.SelectorExpr) (method string, l coq.Expr, ok bool) {
+	t := ctx.typeOf(f.X)
+	if name, ok := syncRefType(t); ok {
+		return ctx.syncMethodName(f, name), ctx.expr(f.X), true
+	}
+	if name, ok := syncValueType(t); ok {
+		// a lock stored by value is used through its address
+		return ctx.syncMethodName(f, name), ctx.syncAddr(f.X), true
 	}
+	if pt, ok := t.(*types.Pointer); ok {
+		if name, ok := atomicType(pt.Elem()); ok {
+			return ctx.atomicMethodName(f, name), ctx.expr(f.X), true
+		}
+	}
+	if name, ok := atomicType(t); ok {
+		// like locks, atomic integers are used through their address
+		return ctx.atomicMethodName(f, name), ctx.syncAddr(f.X), true
+	}
+	return "", nil, false
 }
 
-func (ctx Ctx) condVarMethod(f *ast.SelectorExpr) coq.CallExpr {
-	l := ctx.expr(f.X)
-	switch f.Sel.Name {
-	case "Signal":
-		return coq.NewCallExpr("lock.condSignal", l)
-	case "Broadcast":
-		return coq.NewCallExpr("lock.condBroadcast", l)
-	case "Wait":
-		return coq.NewCallExpr("lock.condWait", l)
-	default:
-		ctx.unsupported(f, "method %s of sync.Cond", f.Sel.Name)
-		return coq.CallExpr{}
+// onceFunc translates the function run by sync.Once.Do, which is usually a
+// function literal (these are not supported elsewhere)
+func (ctx Ctx) onceFunc(e ast.Expr) coq.Expr {
+	f, ok := e.(*ast.FuncLit)
+	if !ok {
+		return ctx.expr(e)
+	}
+	ctx.results = nil
+	return coq.FuncLit{Body: ctx.funcBody(f.Type, f.Body)}
+}
+
+func (ctx Ctx) syncMethodName(f *ast.SelectorExpr, typeName string) string {
+	method, ok := syncMethods[typeName][f.Sel.Name]
+	if !ok {
+		ctx.unsupported(f, "method %s of sync.%s", f.Sel.Name, typeName)
 	}
+	return method
+}
 
+func (ctx Ctx) atomicMethodName(f *ast.SelectorExpr, typeName string) string {
+	if !atomicOps[f.Sel.Name] {
+		ctx.unsupported(f, "method %s of atomic.%s", f.Sel.Name, typeName)
+	}
+	return "atomic." + f.Sel.Name + typeName
 }
+
 func (ctx Ctx) packageMethod(f *ast.SelectorExpr,
 	call *ast.CallExpr) coq.Expr {
 	args := call.Args
-	if isIdent(f.X, "filesys") {
-		return ctx.newCoqCall("FS."+toInitialLower(f.Sel.Name), args)
+	pkg, _ := ctx.importedPkg(f.X)
+	if ext, ok := ctx.externalPackage(pkg); ok {
+		fn := ctx.externalFunc(f, ext, f.Sel.Name)
+		return ctx.externalCall(call, fn, ctx.callArgs(call))
 	}
-	if isIdent(f.X, "disk") {
+	switch pkg {
+	case filesysPkg:
+		return ctx.newCoqCall("FS."+toInitialLower(f.Sel.Name), args)
+	case diskPkg:
 		return ctx.newCoqCall("disk."+f.Sel.Name, args)
-	}
-	if isIdent(f.X, "machine") {
+	case machinePkg:
 		switch f.Sel.Name {
 		case "UInt64Get", "UInt64Put", "UInt32Get", "UInt32Put":
 			return ctx.newCoqCall(f.Sel.Name, args)
@@ -558,67 +810,72 @@ func (ctx Ctx) packageMethod(f *ast.SelectorExpr,
 			ctx.futureWork(f, "unhandled call to machine.%s", f.Sel.Name)
 			return coq.CallExpr{}
 		}
-	}
-	if isIdent(f.X, "log") {
+	case "log":
 		switch f.Sel.Name {
 		case "Print", "Printf", "Println":
 			return coq.LoggingStmt{GoCall: ctx.printGo(call)}
 		}
-	}
-	// FIXME: this hack ensures util.DPrintf runs correctly in goose-nfsd.
-	//
-	//  We always pass #() instead of a slice with the variadic arguments. The
-	//  function is important to handle but has no observable behavior in
-	//  GooseLang, so it's ok to skip the arguments.
-	//
-	// See https://github.com/mit-pdos/goose-nfsd/blob/master/util/util.go
-	if isIdent(f.X, "util") && f.Sel.Name == "DPrintf" {
-		return coq.NewCallExpr("util.DPrintf",
-			ctx.expr(args[0]),
-			ctx.expr(args[1]),
-			coq.UnitLiteral{})
-	}
-	if isIdent(f.X, "fmt") {
+	case "fmt":
 		switch f.Sel.Name {
 		case "Println", "Printf":
 			return coq.LoggingStmt{GoCall: ctx.printGo(call)}
+		case "Errorf":
+			// the arguments are passed as a slice of anyT, like any variadic
+			// call
+			return coq.NewCallExpr("errors.Errorf", ctx.callArgs(call)...)
 		}
-	}
-	if isIdent(f.X, "sync") {
+	case "sync":
 		switch f.Sel.Name {
 		case "NewCond":
 			return ctx.newCoqCall("lock.newCond", args)
 		}
+	case "errors":
+		switch f.Sel.Name {
+		case "New":
+			return ctx.newCoqCall("errors.New", args)
+		}
+	case "sync/atomic":
+		op, ok := atomicFunc(f.Sel.Name)
+		if !ok {
+			ctx.unsupported(f, "atomic.%s", f.Sel.Name)
+			return coq.CallExpr{}
+		}
+		return ctx.newCoqCall(op, args)
 	}
-	pkg := f.X.(*ast.Ident)
-	return ctx.newCoqCall(
-		coq.PackageIdent{Package: pkg.Name, Ident: f.Sel.Name}.Coq(),
-		args)
+	obj, ok := ctx.info.Uses[f.Sel]
+	if !ok || obj.Pkg() == nil || ctx.isBuiltinImport(obj.Pkg().Path()) {
+		ctx.unsupported(f, "call to %s", ctx.printGo(f))
+		return coq.CallExpr{}
+	}
+	return coq.NewCallExpr(ctx.qualifiedName(obj), ctx.callArgs(call)...)
 }
 
 func isDisk(t types.Type) bool {
 	if t, ok := t.(*types.Named); ok {
-		obj := t.Obj()
-		if obj.Pkg().Path() == "github.com/tchajed/goose/machine/disk" &&
-			obj.Name() == "Disk" {
-			return true
-		}
+		return isPkgObject(t.Obj(), diskPkg, "Disk")
 	}
 	return false
 }
 
 func (ctx Ctx) selectorMethod(f *ast.SelectorExpr,
 	call *ast.CallExpr) coq.Expr {
-	args := call.Args
 	selectorType, ok := ctx.getType(f.X)
 	if !ok {
 		return ctx.packageMethod(f, call)
 	}
-	if isLockRef(selectorType) {
-		return ctx.lockMethod(f)
+	if e, ok := ctx.externalMethod(f, call); ok {
+		return e
 	}
-	if isCondVar(selectorType) {
-		return ctx.condVarMethod(f)
+	if sel, ok := ctx.info.Selections[f]; ok && sel.Kind() == types.MethodExpr {
+		// T.Method(x, args) passes the receiver explicitly
+		return coq.NewCallExpr(ctx.methodFunc(f, sel), ctx.callArgs(call)...)
+	}
+	if method, l, ok := ctx.syncMethodFunc(f); ok {
+		if method == "once.do" {
+			return coq.NewCallExpr(method, l, ctx.onceFunc(call.Args[0]))
+		}
+		args := append([]coq.Expr{l}, ctx.callArgs(call)...)
+		return coq.NewCallExpr(method, args...)
 	}
 	if isDisk(selectorType) {
 		method := fmt.Sprintf("disk.%s", f.Sel)
@@ -627,15 +884,66 @@ func (ctx Ctx) selectorMethod(f *ast.SelectorExpr,
 	}
 	structInfo, ok := ctx.getStructInfo(selectorType)
 	if ok {
-		callArgs := append([]ast.Expr{f.X}, args...)
-		return ctx.newCoqCall(
-			coq.StructMethod(structInfo.name, f.Sel.Name),
-			callArgs)
+		callArgs := append([]coq.Expr{ctx.expr(f.X)}, ctx.callArgs(call)...)
+		return coq.NewCallExpr(
+			coq.TypeMethod(structInfo.name, f.Sel.Name),
+			callArgs...)
+	}
+	if sel, ok := ctx.info.Selections[f]; ok && sel.Kind() == types.MethodVal {
+		if recvType, ok := namedReceiver(sel.Recv()); ok {
+			callArgs := append([]coq.Expr{ctx.methodReceiver(f, sel)},
+				ctx.callArgs(call)...)
+			return coq.NewCallExpr(
+				coq.TypeMethod(ctx.qualifiedName(recvType.Obj()), f.Sel.Name),
+				callArgs...)
+		}
 	}
 	ctx.unsupported(f, "unexpected select on type "+selectorType.String())
 	return nil
 }
 
+// methodReceiver translates the receiver x of a method x.M on a named
+// (non-struct) type, taking its address or dereferencing it to match the
+// method's receiver, as Go does implicitly
+func (ctx Ctx) methodReceiver(f *ast.SelectorExpr, sel *types.Selection) coq.Expr {
+	recvType := sel.Obj().Type().(*types.Signature).Recv().Type()
+	if _, ok := ctx.getStructInfo(recvType); ok {
+		return ctx.expr(f.X)
+	}
+	_, wantPtr := recvType.(*types.Pointer)
+	_, isPtr := types.Unalias(ctx.typeOf(f.X)).(*types.Pointer)
+	switch {
+	case wantPtr && !isPtr:
+		// Go implicitly calls (&x).M
+		if ident, ok := f.X.(*ast.Ident); ok && !ctx.identInfo(ident).IsPtrWrapped {
+			ctx.unsupported(f, "pointer method %s on %s, which is not addressable "+
+				"(declare it with var)", f.Sel.Name, ident.Name)
+		}
+		return ctx.refExpr(f.X)
+	case !wantPtr && isPtr:
+		// Go implicitly calls (*x).M
+		return coq.DerefExpr{
+			X:  ctx.expr(f.X),
+			Ty: ctx.coqTypeOfType(f.X, recvType),
+		}
+	}
+	return ctx.expr(f.X)
+}
+
+// namedReceiver returns the named type for a method receiver of type T or *T
+//
+// Interfaces are excluded, since their methods are not statically dispatched.
+func namedReceiver(t types.Type) (*types.Named, bool) {
+	if pt, ok := t.(*types.Pointer); ok {
+		t = pt.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok || types.IsInterface(named) {
+		return nil, false
+	}
+	return named, true
+}
+
 func (ctx Ctx) newCoqCall(method string, es []ast.Expr) coq.CallExpr {
 	var args []coq.Expr
 	for _, e := range es {
@@ -644,6 +952,45 @@ func (ctx Ctx) newCoqCall(method string, es []ast.Expr) coq.CallExpr {
 	return coq.NewCallExpr(method, args...)
 }
 
+// callArgs translates the arguments to a call based on the callee's signature
+//
+// As in Go, the arguments to a variadic parameter are packed into a slice,
+// unless they are already passed as a slice with f(xs...).
+func (ctx Ctx) callArgs(call *ast.CallExpr) []coq.Expr {
+	var args []coq.Expr
+	sig, ok := ctx.typeOf(call.Fun).Underlying().(*types.Signature)
+	if !ok {
+		for _, e := range call.Args {
+			args = append(args, ctx.expr(e))
+		}
+		return args
+	}
+	if !sig.Variadic() || call.Ellipsis != token.NoPos {
+		for i, e := range call.Args {
+			args = append(args, ctx.exprAs(e, sig.Params().At(i).Type()))
+		}
+		return args
+	}
+	numFixed := sig.Params().Len() - 1
+	for i, e := range call.Args[:numFixed] {
+		args = append(args, ctx.exprAs(e, sig.Params().At(i).Type()))
+	}
+	elemTy := sliceElem(sig.Params().At(numFixed).Type())
+	return append(args, ctx.sliceLiteral(call, elemTy, call.Args[numFixed:]))
+}
+
+// sliceLiteral creates a new slice with elements es
+func (ctx Ctx) sliceLiteral(n ast.Node, elemTy types.Type, es []ast.Expr) coq.Expr {
+	if len(es) == 0 {
+		return coq.GallinaIdent("slice.nil")
+	}
+	var elts []coq.Expr
+	for _, e := range es {
+		elts = append(elts, ctx.exprAs(e, elemTy))
+	}
+	return coq.SliceLiteral{Elt: ctx.coqTypeOfType(n, elemTy), Elts: elts}
+}
+
 func (ctx Ctx) methodExpr(call *ast.CallExpr) coq.Expr {
 	args := call.Args
 	// discovered this API via
@@ -673,11 +1020,15 @@ func (ctx Ctx) methodExpr(call *ast.CallExpr) coq.Expr {
 		}
 		// a different type conversion, which is a noop in GooseLang (which is
 		// untyped)
-		return ctx.expr(args[0])
+		return ctx.exprAs(args[0], ctx.typeOf(call))
 	}
 	switch f := call.Fun.(type) {
 	case *ast.Ident:
-		return ctx.newCoqCall(f.Name, args)
+		if _, ok := ctx.info.Uses[f].(*types.Var); ok {
+			// a call to a function value stored in a variable
+			return coq.NewCallValueExpr(ctx.variable(f), ctx.callArgs(call)...)
+		}
+		return coq.NewCallExpr(ctx.funcName(f), ctx.callArgs(call)...)
 	case *ast.SelectorExpr:
 		return ctx.selectorMethod(f, call)
 	}
@@ -716,15 +1067,19 @@ func (ctx Ctx) makeExpr(args []ast.Expr) coq.CallExpr {
 // newExpr parses a call to new() into an appropriate allocation
 func (ctx Ctx) newExpr(s ast.Node, ty ast.Expr) coq.CallExpr {
 	if sel, ok := ty.(*ast.SelectorExpr); ok {
-		if isIdent(sel.X, "sync") && isIdent(sel.Sel, "Mutex") {
-			return coq.NewCallExpr("lock.new")
+		if ctx.isPkg(sel.X, "sync") {
+			switch sel.Sel.Name {
+			case "Mutex":
+				return coq.NewCallExpr("lock.new")
+			case "RWMutex":
+				return coq.NewCallExpr("rwlock.new")
+			case "WaitGroup":
+				return coq.NewCallExpr("waitgroup.new")
+			case "Once":
+				return coq.NewCallExpr("once.new")
+			}
 		}
 	}
-	if t, ok := ctx.typeOf(ty).(*types.Array); ok {
-		return coq.NewCallExpr("zero_array",
-			ctx.coqTypeOfType(ty, t.Elem()),
-			coq.IntLiteral{uint64(t.Len())})
-	}
 	e := coq.NewCallExpr("zero_val", ctx.coqType(ty))
 	// check for new(T) where T is a struct, but not a pointer to a struct
 	// (new(*T) should be translated to ref (zero_val (ptrT ...)) as usual,
@@ -762,10 +1117,17 @@ func getIntegerType(t types.Type) (intTypeInfo, bool) {
 	// causes an untyped literal to become a uint64
 	case types.Uint, types.Int, types.Uint64:
 		return intTypeInfo{width: 64}, true
-	case types.UntypedInt:
+	case types.UntypedInt, types.UntypedRune:
 		return intTypeInfo{isUntyped: true}, true
 	case types.Uint32:
 		return intTypeInfo{width: 32}, true
+	case types.Int32:
+		// only runes, which are never negative, are supported (rune is an
+		// alias for int32, but go/types keeps its name)
+		if basicTy.Name() == "rune" {
+			return intTypeInfo{width: 32}, true
+		}
+		return intTypeInfo{}, false
 	case types.Uint8:
 		return intTypeInfo{width: 8}, true
 	default:
@@ -812,17 +1174,24 @@ func (ctx Ctx) callExpr(s *ast.CallExpr) coq.Expr {
 	}
 	if isIdent(s.Fun, "append") {
 		elemTy := sliceElem(ctx.typeOf(s.Args[0]))
-		if s.Ellipsis == token.NoPos {
+		if s.Ellipsis == token.NoPos && len(s.Args) == 2 {
 			return coq.NewCallExpr("SliceAppend",
 				ctx.coqTypeOfType(s, elemTy),
 				ctx.expr(s.Args[0]),
-				ctx.expr(s.Args[1]))
+				ctx.exprAs(s.Args[1], elemTy))
+		}
+		if s.Ellipsis == token.NoPos {
+			// append(s, x, y, z) appends a slice of all the new elements
+			return coq.NewCallExpr("SliceAppendSlice",
+				ctx.coqTypeOfType(s, elemTy),
+				ctx.expr(s.Args[0]),
+				ctx.sliceLiteral(s, elemTy, s.Args[1:]))
 		}
 		// append(s1, s2...)
 		return coq.NewCallExpr("SliceAppendSlice",
 			ctx.coqTypeOfType(s, elemTy),
 			ctx.expr(s.Args[0]),
-			ctx.expr(s.Args[1]))
+			ctx.exprAs(s.Args[1], ctx.typeOf(s)))
 	}
 	if isIdent(s.Fun, "copy") {
 		return ctx.copyExpr(s, s.Args[0], s.Args[1])
@@ -842,26 +1211,45 @@ func (ctx Ctx) callExpr(s *ast.CallExpr) coq.Expr {
 	if isIdent(s.Fun, "uint8") {
 		return ctx.integerConversion(s, s.Args[0], 8)
 	}
+	if isIdent(s.Fun, "rune") {
+		return ctx.integerConversion(s, s.Args[0], 32)
+	}
 	if isIdent(s.Fun, "panic") {
-		msg := "oops"
-		if e, ok := s.Args[0].(*ast.BasicLit); ok {
-			if e.Kind == token.STRING {
-				v := ctx.info.Types[e].Value
-				msg = constant.StringVal(v)
-			}
+		if v := ctx.info.Types[s.Args[0]].Value; v != nil && v.Kind() == constant.String {
+			return coq.NewCallExpr("Panic", coq.GallinaString(constant.StringVal(v)))
 		}
-		return coq.NewCallExpr("Panic", coq.GallinaString(msg))
+		// the panic value is evaluated first, and can be recovered
+		return coq.NewCallExpr("PanicValue", ctx.expr(s.Args[0]))
+	}
+	if isIdent(s.Fun, "recover") {
+		return coq.NewCallExpr("recover", coq.UnitLiteral{})
 	}
 	return ctx.methodExpr(s)
 }
 
+// funcName gives the Coq name of a function referred to by an identifier,
+// which might be from a dot import
+func (ctx Ctx) funcName(f *ast.Ident) string {
+	if obj := ctx.info.Uses[f]; obj != nil && obj.Pkg() != nil {
+		return ctx.qualifiedName(obj)
+	}
+	return f.Name
+}
+
+// qualifiedName gives the Coq name for a package-level object
+//
+// Objects from other packages are qualified by the Coq module for their import
+// path (see coq.ImportToModule), which is where goose places their translation.
 func (ctx Ctx) qualifiedName(obj types.Object) string {
 	name := obj.Name()
 	if ctx.pkgPath == obj.Pkg().Path() {
 		// no module name needed
 		return name
 	}
-	return fmt.Sprintf("%s.%s", obj.Pkg().Name(), name)
+	return coq.PackageIdent{
+		Package: coq.ImportToModule(obj.Pkg().Path()),
+		Ident:   name,
+	}.Coq()
 }
 
 type structTypeInfo struct {
@@ -877,10 +1265,13 @@ func (ctx Ctx) getStructInfo(t types.Type) (structTypeInfo, bool) {
 		t = pt.Elem()
 	}
 	if t, ok := t.(*types.Named); ok {
-		name := ctx.qualifiedName(t.Obj())
 		if structType, ok := t.Underlying().(*types.Struct); ok {
+			if _, ok := ctx.externalPackage(t.Obj().Pkg().Path()); ok {
+				// the struct's fields are not part of the translation
+				return structTypeInfo{}, false
+			}
 			return structTypeInfo{
-				name:           name,
+				name:           ctx.qualifiedName(t.Obj()),
 				throughPointer: throughPointer,
 				structType:     structType,
 			}, true
@@ -900,17 +1291,25 @@ func (info structTypeInfo) fields() []string {
 func (ctx Ctx) selectExpr(e *ast.SelectorExpr) coq.Expr {
 	selectorType, ok := ctx.getType(e.X)
 	if !ok {
-		if isIdent(e.X, "filesys") {
+		if ctx.isPkg(e.X, filesysPkg) {
 			return coq.GallinaIdent("FS." + e.Sel.Name)
 		}
-		if isIdent(e.X, "disk") {
+		if ctx.isPkg(e.X, diskPkg) {
 			return coq.GallinaIdent("disk." + e.Sel.Name)
 		}
-		if pkg, ok := getIdent(e.X); ok {
-			return coq.PackageIdent{
-				Package: pkg,
-				Ident:   e.Sel.Name,
+		if obj, ok := ctx.info.Uses[e.Sel]; ok && obj.Pkg() != nil {
+			if ext, ok := ctx.externalPackage(obj.Pkg().Path()); ok {
+				return ctx.externalFuncValue(e, ext, obj)
 			}
+			return coq.GallinaIdent(ctx.qualifiedName(obj))
+		}
+	}
+	if sel, ok := ctx.info.Selections[e]; ok {
+		switch sel.Kind() {
+		case types.MethodVal:
+			return ctx.methodValue(e, sel)
+		case types.MethodExpr:
+			return coq.GallinaIdent(ctx.methodFunc(e, sel))
 		}
 	}
 	structInfo, ok := ctx.getStructInfo(selectorType)
@@ -921,6 +1320,47 @@ func (ctx Ctx) selectExpr(e *ast.SelectorExpr) coq.Expr {
 	return nil
 }
 
+// methodFunc gives the name of the function implementing a selected method
+func (ctx Ctx) methodFunc(e *ast.SelectorExpr, sel *types.Selection) string {
+	if f, ok := ctx.externalMethodFunc(e, sel); ok {
+		return ctx.externalValue(e, f)
+	}
+	sig := sel.Obj().Type().(*types.Signature)
+	recvType := sig.Recv().Type()
+	if info, ok := ctx.getStructInfo(recvType); ok {
+		return coq.TypeMethod(info.name, e.Sel.Name)
+	}
+	if named, ok := namedReceiver(recvType); ok {
+		return coq.TypeMethod(ctx.qualifiedName(named.Obj()), e.Sel.Name)
+	}
+	ctx.unsupported(e, "method of type %v", recvType)
+	return ""
+}
+
+// methodValue translates a method value x.M, which is a closure over the
+// receiver x (evaluated when the method value is created)
+func (ctx Ctx) methodValue(e *ast.SelectorExpr, sel *types.Selection) coq.Expr {
+	method := ctx.methodFunc(e, sel)
+	if ctx.typeOf(e).(*types.Signature).Params().Len() > 0 {
+		// methods are curried, so partial application is a closure
+		return coq.NewCallExpr(method, ctx.methodReceiver(e, sel))
+	}
+	// applying method to just the receiver would call it, so delay the call
+	// until the method value is called
+	x := ctx.methodReceiver(e, sel)
+	if ident, ok := e.X.(*ast.Ident); ok && !ctx.identInfo(ident).IsPtrWrapped &&
+		x == coq.IdentExpr(ident.Name) {
+		// immutable, so it can be captured directly
+		return coq.FuncLit{Body: coq.NewCallExpr(method, x)}
+	}
+	recv := "$recv"
+	return coq.LetExpr{
+		Name: recv,
+		Val:  x,
+		Body: coq.FuncLit{Body: coq.NewCallExpr(method, coq.IdentExpr(recv))},
+	}
+}
+
 func (ctx Ctx) structSelector(info structTypeInfo, e *ast.SelectorExpr) coq.StructFieldAccessExpr {
 	return coq.StructFieldAccessExpr{
 		Struct:         info.name,
@@ -930,13 +1370,18 @@ func (ctx Ctx) structSelector(info structTypeInfo, e *ast.SelectorExpr) coq.Stru
 	}
 }
 
+// compositeLiteral translates a slice or struct literal
+//
+// The elements are translated with the slice's element type (or the struct's
+// field types), which gives a nil element its type.
 func (ctx Ctx) compositeLiteral(e *ast.CompositeLit) coq.Expr {
-	if _, ok := ctx.typeOf(e).Underlying().(*types.Slice); ok {
+	if sliceTy, ok := ctx.typeOf(e).Underlying().(*types.Slice); ok {
 		if len(e.Elts) == 0 {
 			return coq.NewCallExpr("nil")
 		}
 		if len(e.Elts) == 1 {
-			return ctx.newCoqCall("SliceSingleton", []ast.Expr{e.Elts[0]})
+			return coq.NewCallExpr("SliceSingleton",
+				ctx.exprAs(e.Elts[0], sliceTy.Elem()))
 		}
 		ctx.unsupported(e, "slice literal with multiple elements")
 		return nil
@@ -961,48 +1406,70 @@ func (ctx Ctx) structLiteral(info structTypeInfo,
 				ctx.noExample(el.Key, "struct field keyed by non-identifier %+v", el.Key)
 				return coq.StructLiteral{}
 			}
-			lit.AddField(ident, ctx.expr(el.Value))
+			lit.AddField(ident, ctx.exprAs(el.Value, structFieldType(info, ident)))
 			foundFields[ident] = true
 		default:
 			ctx.unsupported(e,
 				"un-keyed struct literal field %v", ctx.printGo(el))
 		}
 	}
-	for _, f := range info.fields() {
-		if !foundFields[f] {
-			ctx.unsupported(e, "incomplete struct literal (missing %v)", f)
+	for i := 0; i < info.structType.NumFields(); i++ {
+		f := info.structType.Field(i)
+		if foundFields[f.Name()] {
+			continue
+		}
+		_, isSync := syncValueType(f.Type())
+		_, isAtomic := atomicType(f.Type())
+		if isSync || isAtomic {
+			// locks and atomics are always initialized to their zero value
+			lit.AddField(f.Name(), coq.NewCallExpr("zero_val",
+				ctx.coqTypeOfType(e, f.Type())))
+			continue
 		}
+		ctx.unsupported(e, "incomplete struct literal (missing %v)", f.Name())
 	}
 	return lit
 }
 
+// structFieldType gives the type of a field of a struct (which must exist)
+func structFieldType(info structTypeInfo, name string) types.Type {
+	for i := 0; i < info.structType.NumFields(); i++ {
+		if f := info.structType.Field(i); f.Name() == name {
+			return f.Type()
+		}
+	}
+	panic("no field " + name)
+}
+
 // basicLiteral parses a basic literal
 //
-// (unsigned) ints, strings, and booleans are supported
+// (unsigned) ints, runes, strings, and booleans are supported. Integer literals
+// are translated from their constant value, so any Go syntax for them works.
 func (ctx Ctx) basicLiteral(e *ast.BasicLit) coq.Expr {
 	if e.Kind == token.STRING {
 		v := ctx.info.Types[e].Value
 		s := constant.StringVal(v)
-		if strings.ContainsRune(s, '"') {
-			ctx.unsupported(e, "string literals with quotes")
-		}
 		return coq.StringLiteral{s}
 	}
-	if e.Kind == token.INT {
+	if e.Kind == token.INT || e.Kind == token.CHAR {
 		info, ok := getIntegerType(ctx.typeOf(e))
+		if !ok {
+			ctx.unsupported(e, "literal of type %v", ctx.typeOf(e))
+			return nil
+		}
 		v := ctx.info.Types[e].Value
 		n, ok := constant.Uint64Val(v)
-		if !ok || n < 0 {
+		if !ok {
 			ctx.unsupported(e,
 				"int literals must be positive numbers")
 			return nil
 		}
 		if info.isUint64() {
-			return coq.IntLiteral{n}
+			return coq.IntLiteral{Value: n}
 		} else if info.isUint32() {
-			return coq.Int32Literal{uint32(n)}
+			return coq.Int32Literal{Value: uint32(n)}
 		} else if info.isUint8() {
-			return coq.ByteLiteral{uint8(n)}
+			return coq.ByteLiteral{Value: uint8(n)}
 		}
 	}
 	ctx.unsupported(e, "literal with kind %s", e.Kind)
@@ -1013,7 +1480,7 @@ func (ctx Ctx) isNilCompareExpr(e *ast.BinaryExpr) bool {
 	if !(e.Op == token.EQL || e.Op == token.NEQ) {
 		return false
 	}
-	return ctx.info.Types[e.Y].IsNil()
+	return ctx.info.Types[e.X].IsNil() || ctx.info.Types[e.Y].IsNil()
 }
 
 func (ctx Ctx) binExpr(e *ast.BinaryExpr) coq.Expr {
@@ -1044,32 +1511,193 @@ func (ctx Ctx) binExpr(e *ast.BinaryExpr) coq.Expr {
 		}
 		ok = true
 	}
+	if isString(ctx.typeOf(e.X).Underlying()) {
+		// strings are compared lexicographically, which is not a primitive
+		if f, ok := map[token.Token]string{
+			token.LSS: "StringLt",
+			token.GTR: "StringGt",
+			token.LEQ: "StringLe",
+			token.GEQ: "StringGe",
+		}[e.Op]; ok {
+			return coq.NewCallExpr(f, ctx.expr(e.X), ctx.expr(e.Y))
+		}
+	}
+	if (e.Op == token.EQL || e.Op == token.NEQ) && !ctx.isNilCompareExpr(e) {
+		if eq, ok := ctx.compositeEquals(e); ok {
+			if e.Op == token.NEQ {
+				return coq.NotExpr{X: eq}
+			}
+			return eq
+		}
+	}
 	if ok {
-		expr := coq.BinaryExpr{
+		if ctx.isNilCompareExpr(e) {
+			// nil takes the type of the other side
+			return coq.BinaryExpr{
+				X:  ctx.exprAs(e.X, ctx.typeOf(e.Y)),
+				Op: op,
+				Y:  ctx.exprAs(e.Y, ctx.typeOf(e.X)),
+			}
+		}
+		return coq.BinaryExpr{
 			X:  ctx.expr(e.X),
 			Op: op,
 			Y:  ctx.expr(e.Y),
 		}
-		if ctx.isNilCompareExpr(e) {
-			if _, ok := ctx.typeOf(e.X).(*types.Pointer); ok {
-				expr.Y = coq.Null
-			}
-		}
-		return expr
 	}
 	ctx.unsupported(e, "binary operator %v", e.Op)
 	return nil
 }
 
+// maxUnrolledArray is the longest array that == compares with an unrolled
+// conjunction (longer arrays are compared with a loop, see arrayLoopEquals)
+const maxUnrolledArray = 16
+
+// compositeEquals translates an equality comparison between structs or
+// arrays, which GooseLang equality does not support, so they are compared
+// element-wise. It also rejects other comparisons that cannot be modeled.
+func (ctx Ctx) compositeEquals(e *ast.BinaryExpr) (coq.Expr, bool) {
+	t := ctx.typeOf(e.X)
+	switch t.Underlying().(type) {
+	case *types.Struct, *types.Array:
+	default:
+		ctx.checkComparable(e, t)
+		return nil, false
+	}
+	var bindings []coq.LetExpr
+	operand := func(e ast.Expr, name string) coq.Expr {
+		if ctx.isPureExpr(e) {
+			return ctx.expr(e)
+		}
+		bindings = append(bindings, coq.LetExpr{Name: name, Val: ctx.expr(e)})
+		return coq.IdentExpr(name)
+	}
+	x := operand(e.X, "$x")
+	y := operand(e.Y, "$y")
+	var eq coq.Expr = ctx.valueEquals(e, t, x, y, 0)
+	for i := len(bindings) - 1; i >= 0; i-- {
+		bindings[i].Body = eq
+		eq = bindings[i]
+	}
+	return eq, true
+}
+
+// checkComparable reports an error if values of type t cannot be compared
+// with GooseLang's equality
+func (ctx Ctx) checkComparable(e ast.Node, t types.Type) {
+	if _, ok := t.Underlying().(*types.Interface); ok && !isErrorType(t) {
+		// the dynamic values might be structs, which cannot be compared
+		ctx.unsupported(e, "comparison of interface values of type %v", t)
+	}
+}
+
+// valueEquals compares values x and y of type t, element-wise for structs
+// and arrays
+//
+// depth counts the enclosing array loops, so nested loops use distinct names.
+func (ctx Ctx) valueEquals(e ast.Node, t types.Type, x, y coq.Expr, depth int) coq.Expr {
+	var conjuncts []coq.Expr
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		info, ok := ctx.getStructInfo(t)
+		if !ok {
+			ctx.unsupported(e, "comparison of anonymous structs")
+			return nil
+		}
+		field := func(x coq.Expr, name string) coq.Expr {
+			return coq.StructFieldAccessExpr{Struct: info.name, Field: name, X: x}
+		}
+		for i := 0; i < u.NumFields(); i++ {
+			f := u.Field(i)
+			if f.Name() == "_" {
+				continue
+			}
+			conjuncts = append(conjuncts, ctx.valueEquals(e, f.Type(),
+				field(x, f.Name()), field(y, f.Name()), depth))
+		}
+	case *types.Array:
+		if u.Len() > maxUnrolledArray {
+			return ctx.arrayLoopEquals(e, u, x, y, depth)
+		}
+		elt := ctx.coqTypeOfType(e, u.Elem())
+		for i := int64(0); i < u.Len(); i++ {
+			idx := coq.IntLiteral{Value: uint64(i)}
+			conjuncts = append(conjuncts, ctx.valueEquals(e, u.Elem(),
+				coq.NewCallExpr("ArrayGet", elt, x, idx),
+				coq.NewCallExpr("ArrayGet", elt, y, idx), depth))
+		}
+	default:
+		ctx.checkComparable(e, t)
+		return coq.BinaryExpr{X: x, Op: coq.OpEquals, Y: y}
+	}
+	if len(conjuncts) == 0 {
+		return coq.True
+	}
+	eq := conjuncts[0]
+	for _, c := range conjuncts[1:] {
+		eq = coq.BinaryExpr{X: eq, Op: coq.OpLAnd, Y: c}
+	}
+	return eq
+}
+
+// arrayLoopEquals compares arrays x and y of type t with a loop over their
+// elements, which clears a flag if any pair of elements differs
+func (ctx Ctx) arrayLoopEquals(e ast.Node, t *types.Array, x, y coq.Expr, depth int) coq.Expr {
+	eqVar := coq.IdentExpr(fmt.Sprintf("$eq%d", depth))
+	i := coq.IdentExpr(fmt.Sprintf("$i%d", depth))
+	elt := ctx.coqTypeOfType(e, t.Elem())
+	boolT := coq.TypeIdent("boolT")
+	eltEq := ctx.valueEquals(e, t.Elem(),
+		coq.NewCallExpr("ArrayGet", elt, x, i),
+		coq.NewCallExpr("ArrayGet", elt, y, i), depth+1)
+	return coq.LetExpr{
+		Name: string(eqVar),
+		Val:  coq.RefExpr{X: coq.True, Ty: boolT},
+		Body: coq.BlockExpr{Bindings: []coq.Binding{
+			coq.NewAnon(coq.IntLoopExpr{
+				Key: &i,
+				Ty:  coq.TypeIdent("uint64T"),
+				N:   coq.IntLiteral{Value: uint64(t.Len())},
+				Body: coq.BlockExpr{Bindings: []coq.Binding{
+					coq.NewAnon(coq.IfExpr{
+						Cond: coq.NotExpr{X: eltEq},
+						Then: coq.StoreStmt{Dst: eqVar, Ty: boolT, X: coq.False},
+						Else: coq.UnitLiteral{},
+					}),
+				}},
+			}),
+			coq.NewAnon(coq.DerefExpr{X: eqVar, Ty: boolT}),
+		}},
+	}
+}
+
 func (ctx Ctx) sliceExpr(e *ast.SliceExpr) coq.Expr {
 	if e.Slice3 {
 		ctx.unsupported(e, "3-index slice")
 		return nil
 	}
-	x := ctx.expr(e.X)
+	if isString(ctx.typeOf(e.X).Underlying()) {
+		return ctx.stringSliceExpr(e)
+	}
+	var x coq.Expr
+	var elemTy types.Type
+	if arrTy, ok := arrayOrPtrToArray(ctx.typeOf(e.X)); ok {
+		// slicing an array creates a slice that aliases the array's storage
+		elemTy = arrTy.Elem()
+		x = coq.NewCallExpr("ArrayToSlice",
+			ctx.coqTypeOfType(e, elemTy),
+			ctx.arrayAddr(e.X),
+			coq.IntLiteral{Value: uint64(arrTy.Len())})
+		if e.Low == nil && e.High == nil {
+			return x
+		}
+	} else {
+		elemTy = sliceElem(ctx.typeOf(e.X).Underlying())
+		x = ctx.expr(e.X)
+	}
 	if e.Low != nil && e.High == nil {
 		return coq.NewCallExpr("SliceSkip",
-			ctx.coqTypeOfType(e, sliceElem(ctx.typeOf(e.X))),
+			ctx.coqTypeOfType(e, elemTy),
 			x, ctx.expr(e.Low))
 	}
 	if e.Low == nil && e.High != nil {
@@ -1078,7 +1706,7 @@ func (ctx Ctx) sliceExpr(e *ast.SliceExpr) coq.Expr {
 	}
 	if e.Low != nil && e.High != nil {
 		return coq.NewCallExpr("SliceSubslice",
-			ctx.coqTypeOfType(e, sliceElem(ctx.typeOf(e.X))),
+			ctx.coqTypeOfType(e, elemTy),
 			x, ctx.expr(e.Low), ctx.expr(e.High))
 	}
 	if e.Low == nil && e.High == nil {
@@ -1087,25 +1715,66 @@ func (ctx Ctx) sliceExpr(e *ast.SliceExpr) coq.Expr {
 	return nil
 }
 
+// stringSliceExpr translates a substring operation s[a:b]
+func (ctx Ctx) stringSliceExpr(e *ast.SliceExpr) coq.Expr {
+	x := ctx.expr(e.X)
+	if e.Low != nil && e.High == nil {
+		return coq.NewCallExpr("StringSkip", x, ctx.expr(e.Low))
+	}
+	if e.Low == nil && e.High != nil {
+		return coq.NewCallExpr("StringTake", x, ctx.expr(e.High))
+	}
+	if e.Low != nil && e.High != nil {
+		return coq.NewCallExpr("StringSubslice",
+			x, ctx.expr(e.Low), ctx.expr(e.High))
+	}
+	ctx.unsupported(e, "complete slice doesn't do anything")
+	return nil
+}
+
+// nilExpr translates a nil outside of any context that gives it a type
+//
+// go/types always records nil as untyped, so this is an error unless the nil
+// is in a context that exprAs handles.
 func (ctx Ctx) nilExpr(e *ast.Ident) coq.Expr {
-	t := ctx.typeOf(e)
-	switch t.(type) {
-	case *types.Pointer:
-		return coq.GallinaIdent("null")
+	return ctx.nilOf(e, ctx.typeOf(e))
+}
+
+// nilOf translates a nil used as a value of type t
+//
+// go/types leaves nil untyped, so callers determine t from the context (see
+// exprAs).
+func (ctx Ctx) nilOf(e ast.Expr, t types.Type) coq.Expr {
+	switch t.Underlying().(type) {
+	case *types.Pointer, *types.Interface, *types.Map,
+		*types.Signature, *types.Chan:
+		// all of these are references
+		return coq.Null
 	case *types.Slice:
 		return coq.GallinaIdent("slice.nil")
-	case *types.Basic:
-		// TODO: this gets triggered for all of our unit tests because the
-		//  nil identifier is mapped to an untyped nil object.
-		//  This seems wrong; the runtime representation of each of these
-		//  uses depends on the type, so Go must know how they're being used.
-		return coq.GallinaIdent("slice.nil")
 	default:
-		ctx.unsupported(e, "nil of type %v (not pointer or slice)", t)
+		ctx.unsupported(e, "nil of type %v", t)
 		return nil
 	}
 }
 
+func isNil(e ast.Expr) bool {
+	if e, ok := e.(*ast.ParenExpr); ok {
+		return isNil(e.X)
+	}
+	return isIdent(e, "nil")
+}
+
+// exprAs translates e where a value of type t is expected
+//
+// This only differs from expr for nil, which takes its type from t.
+func (ctx Ctx) exprAs(e ast.Expr, t types.Type) coq.Expr {
+	if isNil(e) && ctx.info.Types[e].IsNil() {
+		return ctx.nilOf(e, t)
+	}
+	return ctx.expr(e)
+}
+
 func (ctx Ctx) unaryExpr(e *ast.UnaryExpr) coq.Expr {
 	if e.Op == token.NOT {
 		return coq.NotExpr{ctx.expr(e.X)}
@@ -1120,6 +1789,9 @@ func (ctx Ctx) unaryExpr(e *ast.UnaryExpr) coq.Expr {
 				return coq.NewCallExpr("SliceRef",
 					ctx.expr(x.X), ctx.expr(x.Index))
 			}
+			if ty, ok := arrayOrPtrToArray(ctx.typeOf(x.X)); ok {
+				return ctx.arrayRef(x, ty)
+			}
 		}
 		if info, ok := ctx.getStructInfo(ctx.typeOf(e.X)); ok {
 			structLit, ok := e.X.(*ast.CompositeLit)
@@ -1169,12 +1841,52 @@ func (ctx Ctx) identExpr(e *ast.Ident) coq.Expr {
 		}
 		ctx.unsupported(e, "special identifier")
 	}
+	if _, ok := ctx.info.Uses[e].(*types.Func); ok {
+		// a top-level function used as a value
+		return coq.GallinaIdent(ctx.funcName(e))
+	}
+	if obj := ctx.info.Uses[e]; obj != nil && obj.Pkg() != nil &&
+		obj.Pkg().Path() != ctx.pkgPath && obj.Parent() == obj.Pkg().Scope() {
+		// a constant from a dot import
+		return coq.GallinaIdent(ctx.qualifiedName(obj))
+	}
 	return ctx.variable(e)
 }
 
-func (ctx Ctx) indexExpr(e *ast.IndexExpr, isSpecial bool) coq.CallExpr {
+// arrayAddr translates an addressable array expression (or a pointer to an
+// array) to a pointer to the array's storage
+func (ctx Ctx) arrayAddr(e ast.Expr) coq.Expr {
+	if _, ok := ctx.typeOf(e).Underlying().(*types.Pointer); ok {
+		return ctx.expr(e)
+	}
+	if ident, ok := e.(*ast.Ident); ok && !ctx.identInfo(ident).IsPtrWrapped {
+		ctx.unsupported(e, "array %s is not addressable\n\t(declare it with 'var' to modify or slice it)", ident.Name)
+	}
+	return ctx.refExpr(e)
+}
+
+// arrayRef translates &a[i] for an array a to a pointer to the element
+func (ctx Ctx) arrayRef(e *ast.IndexExpr, ty *types.Array) coq.Expr {
+	return coq.NewCallExpr("ArrayRef",
+		ctx.coqTypeOfType(e, ty.Elem()),
+		ctx.arrayAddr(e.X), ctx.expr(e.Index))
+}
+
+func (ctx Ctx) indexExpr(e *ast.IndexExpr, isSpecial bool) coq.Expr {
 	xTy := ctx.typeOf(e.X).Underlying()
 	switch xTy := xTy.(type) {
+	case *types.Array:
+		// arrays are values, so this is a pure lookup
+		return coq.NewCallExpr("ArrayGet",
+			ctx.coqTypeOfType(e, xTy.Elem()),
+			ctx.expr(e.X), ctx.expr(e.Index))
+	case *types.Pointer:
+		if ty, ok := arrayOrPtrToArray(xTy); ok {
+			return coq.DerefExpr{
+				X:  ctx.arrayRef(e, ty),
+				Ty: ctx.coqTypeOfType(e, ty.Elem()),
+			}
+		}
 	case *types.Map:
 		e := coq.NewCallExpr("MapGet", ctx.expr(e.X), ctx.expr(e.Index))
 		if !isSpecial {
@@ -1185,6 +1897,11 @@ func (ctx Ctx) indexExpr(e *ast.IndexExpr, isSpecial bool) coq.CallExpr {
 		return coq.NewCallExpr("SliceGet",
 			ctx.coqTypeOfType(e, xTy.Elem()),
 			ctx.expr(e.X), ctx.expr(e.Index))
+	case *types.Basic:
+		if xTy.Kind() == types.String {
+			return coq.NewCallExpr("StringGet",
+				ctx.expr(e.X), ctx.expr(e.Index))
+		}
 	}
 	ctx.unsupported(e, "index into unknown type %v", xTy)
 	return coq.CallExpr{}
@@ -1236,6 +1953,8 @@ func (ctx Ctx) exprSpecial(e ast.Expr, isSpecial bool) coq.Expr {
 	case *ast.TypeAssertExpr:
 		// TODO: do something with the type
 		return ctx.expr(e.X)
+	case *ast.FuncLit:
+		ctx.futureWork(e, "function literal outside of go, defer or sync.Once.Do")
 	default:
 		ctx.unsupported(e, "unexpected expr")
 	}
@@ -1401,35 +2120,81 @@ func (ctx Ctx) ifStmt(s *ast.IfStmt, c *cursor, loopVar *string) coq.Binding {
 	return coq.Binding{}
 }
 
-func (ctx Ctx) loopVar(s ast.Stmt) (ident *ast.Ident, init coq.Expr) {
-	initAssign, ok := s.(*ast.AssignStmt)
-	if !ok ||
-		len(initAssign.Lhs) > 1 ||
-		len(initAssign.Rhs) > 1 ||
-		initAssign.Tok != token.DEFINE {
-		ctx.unsupported(s, "loop initialization must be a single assignment")
-		return nil, nil
-	}
-	lhs, ok := initAssign.Lhs[0].(*ast.Ident)
-	if !ok {
-		ctx.nope(s, "initialization must define an identifier")
-	}
-	rhs := initAssign.Rhs[0]
-	return lhs, ctx.expr(rhs)
+// mentionsAny reports whether e refers to any of names
+func mentionsAny(e ast.Expr, names map[string]bool) bool {
+	found := false
+	ast.Inspect(e, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && names[id.Name] {
+			found = true
+		}
+		return !found
+	})
+	return found
 }
 
-func (ctx Ctx) forStmt(s *ast.ForStmt) coq.ForLoopExpr {
-	var init = coq.NewAnon(coq.Skip)
-	var ident *ast.Ident
-	loopVar := new(string)
-	if s.Init != nil {
-		ident, _ = ctx.loopVar(s.Init)
+// forInit translates the initialization statement of a for loop
+//
+// Variables defined by the initialization are pointer-wrapped, since the post
+// statement updates them.
+func (ctx Ctx) forInit(s ast.Stmt) []coq.Binding {
+	if s == nil {
+		return []coq.Binding{coq.NewAnon(coq.Skip)}
+	}
+	define, ok := s.(*ast.AssignStmt)
+	if !ok || define.Tok != token.DEFINE {
+		return []coq.Binding{ctx.stmt(s, &cursor{nil}, nil)}
+	}
+	if len(define.Lhs) != len(define.Rhs) {
+		ctx.unsupported(s, "loop initialization from a multiple-value expression")
+		return nil
+	}
+	var idents []*ast.Ident
+	for _, lhs := range define.Lhs {
+		ident, ok := lhs.(*ast.Ident)
+		if !ok {
+			ctx.nope(s, "initialization must define an identifier")
+		}
+		idents = append(idents, ident)
+	}
+	// the values are evaluated before any of the variables are in scope, so
+	// they are bound to temporaries if one refers to a shadowed variable
+	defined := make(map[string]bool)
+	shadows := false
+	for i, ident := range idents {
+		shadows = shadows || mentionsAny(define.Rhs[i], defined)
+		defined[ident.Name] = true
+	}
+	var bindings []coq.Binding
+	vals := make([]coq.Expr, len(idents))
+	for i, rhs := range define.Rhs {
+		vals[i] = ctx.exprAs(rhs, ctx.typeOf(idents[i]))
+		if shadows {
+			name := fmt.Sprintf("$v%d", i)
+			bindings = append(bindings, coq.Binding{
+				Names: []string{name}, Expr: vals[i],
+			})
+			vals[i] = coq.IdentExpr(name)
+		}
+	}
+	for i, ident := range idents {
 		ctx.addDef(ident, identInfo{
 			IsPtrWrapped: true,
 		})
-		init = ctx.stmt(s.Init, &cursor{nil}, nil)
-		loopVar = &ident.Name
+		bindings = append(bindings, coq.Binding{
+			Names: []string{ident.Name},
+			Expr: coq.RefExpr{
+				X:  vals[i],
+				Ty: ctx.coqTypeOfType(ident, ctx.typeOf(ident)),
+			},
+		})
 	}
+	return bindings
+}
+
+func (ctx Ctx) forStmt(s *ast.ForStmt) coq.ForLoopExpr {
+	// this marks the body and post statement as being in a loop
+	loopVar := new(string)
+	init := ctx.forInit(s.Init)
 	var cond coq.Expr = coq.True
 	if s.Cond != nil {
 		cond = ctx.expr(s.Cond)
@@ -1471,7 +2236,10 @@ func getIdentOrAnonymous(e ast.Expr) (ident string, ok bool) {
 }
 
 func (ctx Ctx) getMapClearIdiom(s *ast.RangeStmt) coq.Expr {
-	if _, ok := ctx.typeOf(s.X).(*types.Map); !ok {
+	if _, ok := ctx.typeOf(s.X).Underlying().(*types.Map); !ok {
+		return nil
+	}
+	if s.Tok != token.DEFINE {
 		return nil
 	}
 	key, ok := getIdent(s.Key)
@@ -1509,21 +2277,18 @@ func (ctx Ctx) mapRangeStmt(s *ast.RangeStmt) coq.Expr {
 	if expr := ctx.getMapClearIdiom(s); expr != nil {
 		return expr
 	}
-	key, ok := getIdentOrAnonymous(s.Key)
-	if !ok {
-		ctx.nope(s.Key, "range with non-ident key")
-		return nil
-	}
-	val, ok := getIdentOrAnonymous(s.Value)
-	if !ok {
-		ctx.nope(s.Value, "range with non-ident value")
-		return nil
+	key, val, assigns := ctx.rangeBinders(s)
+	name := func(b coq.Binder) string {
+		if b == nil {
+			return "_"
+		}
+		return string(*b)
 	}
 	return coq.MapIterExpr{
-		KeyIdent:   key,
-		ValueIdent: val,
+		KeyIdent:   name(key),
+		ValueIdent: name(val),
 		Map:        ctx.expr(s.X),
-		Body:       ctx.blockStmt(s.Body, nil),
+		Body:       ctx.rangeBody(s, assigns, nil),
 	}
 }
 
@@ -1542,44 +2307,122 @@ func (ctx Ctx) identBinder(id *ast.Ident) coq.Binder {
 	return &e
 }
 
-func (ctx Ctx) sliceRangeStmt(s *ast.RangeStmt) coq.Expr {
-	var loopVar *string
-	key := getIdentOrNil(s.Key)
-	val := getIdentOrNil(s.Value)
-	if key != nil {
-		ctx.addDef(key, identInfo{
-			IsPtrWrapped: false,
-			IsMacro:      false,
-		})
-		loopVar = &key.Name
+// rangeBinders determines what a range statement binds for its key and
+// value (nil if they are omitted or blank)
+//
+// With :=, the loop binds the new variables directly. With =, the loop binds
+// fresh names, and the returned assignments, which the body starts with,
+// store them to the existing variables.
+func (ctx Ctx) rangeBinders(s *ast.RangeStmt) (key, val coq.Binder, assigns []coq.Binding) {
+	binder := func(e ast.Expr, fresh string) coq.Binder {
+		if e == nil || isIdent(e, "_") {
+			return nil
+		}
+		if s.Tok == token.DEFINE {
+			id, ok := e.(*ast.Ident)
+			if !ok {
+				ctx.nope(e, "range defining a non-identifier")
+			}
+			ctx.addDef(id, identInfo{
+				IsPtrWrapped: false,
+				IsMacro:      false,
+			})
+			return ctx.identBinder(id)
+		}
+		name := coq.IdentExpr(fresh)
+		assigns = append(assigns, ctx.assignFromTo(s, e, name))
+		return &name
 	}
-	if val != nil {
-		ctx.addDef(val, identInfo{
-			IsPtrWrapped: false,
-			IsMacro:      false,
-		})
+	key = binder(s.Key, "$key")
+	val = binder(s.Value, "$val")
+	return
+}
+
+// rangeBranch finds a break or continue that applies to a range loop with the
+// given body (rather than to a nested loop)
+func rangeBranch(body *ast.BlockStmt) *ast.BranchStmt {
+	var branch *ast.BranchStmt
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.BranchStmt:
+			branch = n
+		case *ast.ForStmt, *ast.RangeStmt, *ast.FuncLit:
+			return false
+		}
+		return branch == nil
+	})
+	return branch
+}
+
+// rangeBody translates the body of a range loop, which starts with assigns
+//
+// As in forStmt, loopVar marks the body as being in a loop (so that a return
+// in it is rejected); only map iteration passes nil. The loop runs the body
+// for every element and ignores its result, so the body cannot break or
+// continue.
+func (ctx Ctx) rangeBody(s *ast.RangeStmt, assigns []coq.Binding,
+	loopVar *string) coq.BlockExpr {
+	if branch := rangeBranch(s.Body); branch != nil {
+		ctx.futureWork(branch, "%s in range loop", branch.Tok)
 	}
+	body := ctx.blockStmt(s.Body, loopVar)
+	return coq.BlockExpr{Bindings: append(assigns, body.Bindings...)}
+}
+
+func (ctx Ctx) sliceRangeStmt(s *ast.RangeStmt) coq.Expr {
+	key, val, assigns := ctx.rangeBinders(s)
 	return coq.SliceLoopExpr{
-		Key:   ctx.identBinder(key),
-		Val:   ctx.identBinder(val),
+		Key:   key,
+		Val:   val,
 		Slice: ctx.expr(s.X),
 		Ty:    ctx.coqTypeOfType(s.X, sliceElem(ctx.typeOf(s.X))),
-		Body:  ctx.blockStmt(s.Body, loopVar),
+		Body:  ctx.rangeBody(s, assigns, new(string)),
+	}
+}
+
+// stringRangeStmt translates a loop over the runes in a string
+//
+// The key is the byte offset of each rune and the value is the decoded rune
+// (as in Go, invalid UTF-8 decodes to the replacement character).
+func (ctx Ctx) stringRangeStmt(s *ast.RangeStmt) coq.Expr {
+	key, val, assigns := ctx.rangeBinders(s)
+	return coq.StringLoopExpr{
+		Key:  key,
+		Val:  val,
+		Str:  ctx.expr(s.X),
+		Body: ctx.rangeBody(s, assigns, new(string)),
+	}
+}
+
+// intRangeStmt translates a loop over the integers from 0 to n (exclusive)
+func (ctx Ctx) intRangeStmt(s *ast.RangeStmt) coq.Expr {
+	key, _, assigns := ctx.rangeBinders(s)
+	return coq.IntLoopExpr{
+		Key:  key,
+		Ty:   ctx.coqTypeOfType(s.X, ctx.typeOf(s.X)),
+		N:    ctx.expr(s.X),
+		Body: ctx.rangeBody(s, assigns, new(string)),
 	}
 }
 
 func (ctx Ctx) rangeStmt(s *ast.RangeStmt) coq.Expr {
-	switch ctx.typeOf(s.X).(type) {
+	switch t := ctx.typeOf(s.X).Underlying().(type) {
 	case *types.Map:
 		return ctx.mapRangeStmt(s)
 	case *types.Slice:
 		return ctx.sliceRangeStmt(s)
-	default:
-		ctx.unsupported(s,
-			"range over %v (only maps and slices are supported)",
-			ctx.typeOf(s.X))
-		return nil
+	case *types.Basic:
+		if isString(t) {
+			return ctx.stringRangeStmt(s)
+		}
+		if t.Info()&types.IsInteger != 0 {
+			return ctx.intRangeStmt(s)
+		}
 	}
+	ctx.unsupported(s,
+		"range over %v (only maps, slices, strings and integers are supported)",
+		ctx.typeOf(s.X))
+	return nil
 }
 
 func (ctx Ctx) referenceTo(rhs ast.Expr) coq.Expr {
@@ -1644,7 +2487,11 @@ func (ctx Ctx) varSpec(s *ast.ValueSpec) coq.Binding {
 		rhs = coq.NewCallExpr("ref",
 			coq.NewCallExpr("zero_val", ctx.coqTypeOfType(s, ty)))
 	} else {
-		rhs = ctx.referenceTo(s.Values[0])
+		ty := ctx.typeOf(lhs)
+		rhs = coq.RefExpr{
+			X:  ctx.exprAs(s.Values[0], ty),
+			Ty: ctx.coqTypeOfType(s, ty),
+		}
 	}
 	return coq.Binding{
 		Names: []string{lhs.Name},
@@ -1700,6 +2547,12 @@ func (ctx Ctx) refExpr(s ast.Expr) coq.Expr {
 		}
 		return coq.NewCallExpr("struct.fieldRef", coq.StructDesc(info.name),
 			coq.GallinaString(fieldName), structExpr)
+	case *ast.IndexExpr:
+		if ty, ok := arrayOrPtrToArray(ctx.typeOf(s.X)); ok {
+			return ctx.arrayRef(s, ty)
+		}
+		ctx.futureWork(s, "reference to other types of expressions")
+		return nil
 	// TODO: should move support for slice indexing here as well
 	default:
 		ctx.futureWork(s, "reference to other types of expressions")
@@ -1726,7 +2579,14 @@ func (ctx Ctx) assignFromTo(s ast.Node,
 		}
 		ctx.unsupported(s, "variable %s is not assignable\n\t(declare it with 'var' to pointer-wrap in GooseLang and support re-assignment)", lhs.Name)
 	case *ast.IndexExpr:
-		targetTy := ctx.typeOf(lhs.X)
+		if ty, ok := arrayOrPtrToArray(ctx.typeOf(lhs.X)); ok {
+			return coq.NewAnon(coq.StoreStmt{
+				Dst: ctx.arrayRef(lhs, ty),
+				Ty:  ctx.coqTypeOfType(lhs, ty.Elem()),
+				X:   rhs,
+			})
+		}
+		targetTy := ctx.typeOf(lhs.X).Underlying()
 		switch targetTy := targetTy.(type) {
 		case *types.Slice:
 			value := rhs
@@ -1766,6 +2626,11 @@ func (ctx Ctx) assignFromTo(s ast.Node,
 		})
 	case *ast.SelectorExpr:
 		ty := ctx.typeOf(lhs.X)
+		if name, ok := syncValueType(ty); ok && name == "Cond" && lhs.Sel.Name == "L" {
+			// initialization of a condition variable stored by value
+			return coq.NewAnon(coq.NewCallExpr("lock.condSetLock",
+				ctx.syncAddr(lhs.X), rhs))
+		}
 		info, ok := ctx.getStructInfo(ty)
 		var structExpr coq.Expr
 		// TODO: this adjusts for pointer-wrapping in refExpr, but there should
@@ -1796,11 +2661,14 @@ func (ctx Ctx) assignStmt(s *ast.AssignStmt, c *cursor, loopVar *string) coq.Bin
 	if s.Tok == token.DEFINE {
 		return ctx.defineStmt(s)
 	}
+	if s.Tok == token.ASSIGN && len(s.Lhs) > 1 && len(s.Lhs) == len(s.Rhs) {
+		return coq.NewAnon(ctx.parallelAssign(s))
+	}
 	if len(s.Lhs) > 1 || len(s.Rhs) > 1 {
 		ctx.unsupported(s, "multiple assignment")
 	}
 	lhs := s.Lhs[0]
-	rhs := ctx.expr(s.Rhs[0])
+	rhs := ctx.exprAs(s.Rhs[0], ctx.typeOf(lhs))
 	assignOps := map[token.Token]coq.BinOp{
 		token.ADD_ASSIGN: coq.OpPlus,
 		token.SUB_ASSIGN: coq.OpMinus,
@@ -1817,6 +2685,31 @@ func (ctx Ctx) assignStmt(s *ast.AssignStmt, c *cursor, loopVar *string) coq.Bin
 	return ctx.assignFromTo(s, lhs, rhs)
 }
 
+// parallelAssign translates an assignment x, y = a, b, which evaluates all of
+// the values before assigning any of them
+func (ctx Ctx) parallelAssign(s *ast.AssignStmt) coq.BlockExpr {
+	var bindings []coq.Binding
+	vals := make([]coq.Expr, len(s.Rhs))
+	for i, rhs := range s.Rhs {
+		vals[i] = ctx.exprAs(rhs, ctx.typeOf(s.Lhs[i]))
+		if ctx.isPureExpr(rhs) {
+			continue
+		}
+		name := fmt.Sprintf("$a%d", i)
+		bindings = append(bindings, coq.Binding{
+			Names: []string{name}, Expr: vals[i],
+		})
+		vals[i] = coq.IdentExpr(name)
+	}
+	for i, lhs := range s.Lhs {
+		if isIdent(lhs, "_") {
+			continue
+		}
+		bindings = append(bindings, ctx.assignFromTo(s, lhs, vals[i]))
+	}
+	return coq.BlockExpr{Bindings: bindings}
+}
+
 func (ctx Ctx) incDecStmt(stmt *ast.IncDecStmt, loopVar *string) coq.Binding {
 	ident := getIdentOrNil(stmt.X)
 	if ident == nil {
@@ -1838,14 +2731,150 @@ func (ctx Ctx) incDecStmt(stmt *ast.IncDecStmt, loopVar *string) coq.Binding {
 	})
 }
 
-func (ctx Ctx) spawnExpr(thread ast.Expr) coq.SpawnExpr {
-	f, ok := thread.(*ast.FuncLit)
-	if !ok {
-		ctx.futureWork(thread,
-			"only function literal spawns are supported")
-		return coq.SpawnExpr{}
+// isPureExpr reports whether translating e gives a value that does not depend
+// on when it is evaluated (so that it need not be bound to a name)
+func (ctx Ctx) isPureExpr(e ast.Expr) bool {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		return true
+	case *ast.Ident:
+		return ctx.goBuiltin(e) || !ctx.identInfo(e).IsPtrWrapped
+	}
+	return false
+}
+
+// delayedFuncValue translates the function being called in a go or defer
+// statement, and reports whether it is pure
+func (ctx Ctx) delayedFuncValue(kw string, f ast.Expr) (fn coq.Expr, pure bool) {
+	switch f := f.(type) {
+	case *ast.Ident:
+		switch ctx.info.Uses[f].(type) {
+		case *types.Func:
+			return coq.GallinaIdent(ctx.funcName(f)), true
+		case *types.Var:
+			return ctx.variable(f), ctx.isPureExpr(f)
+		}
+	case *ast.SelectorExpr:
+		if sel, ok := ctx.info.Selections[f]; ok {
+			switch sel.Kind() {
+			case types.MethodVal:
+				// evaluates the receiver
+				return ctx.methodValue(f, sel), false
+			case types.MethodExpr:
+				return coq.GallinaIdent(ctx.methodFunc(f, sel)), true
+			}
+		}
+		if fun, ok := ctx.info.Uses[f.Sel].(*types.Func); ok {
+			if ext, ok := ctx.externalPackage(fun.Pkg().Path()); ok {
+				return ctx.externalFuncValue(f, ext, fun), true
+			}
+			if !ctx.isBuiltinImport(fun.Pkg().Path()) {
+				return coq.GallinaIdent(ctx.qualifiedName(fun)), true
+			}
+		}
 	}
-	return coq.SpawnExpr{Body: ctx.blockStmt(f.Body, nil)}
+	ctx.unsupported(f, "%s statement calling %s", kw, ctx.printGo(f))
+	return nil, false
+}
+
+// delayedCall translates the call in a go or defer statement
+//
+// As in Go, the function value and its arguments are evaluated immediately,
+// by the returned bindings, and only the returned call is delayed. The
+// bindings (which include a function literal's parameters) must be scoped
+// with delayedScope, so they do not shadow variables later in the function.
+func (ctx Ctx) delayedCall(kw string, call *ast.CallExpr) ([]coq.Binding, coq.BlockExpr) {
+	var bindings []coq.Binding
+	args := ctx.callArgs(call)
+	if f, ok := call.Fun.(*ast.FuncLit); ok {
+		// bind the parameters directly, since the body can refer to them
+		var names []*ast.Ident
+		for _, p := range f.Type.Params.List {
+			names = append(names, p.Names...)
+		}
+		ctx.paramList(f.Type.Params)
+		for i, name := range names {
+			bindings = append(bindings, coq.Binding{
+				Names: []string{name.Name},
+				Expr:  args[i],
+			})
+		}
+		return bindings, ctx.blockStmt(f.Body, nil)
+	}
+	if f, ok := call.Fun.(*ast.SelectorExpr); ok {
+		if method, l, ok := ctx.syncMethodFunc(f); ok {
+			// the library function takes the receiver as an argument
+			if !ctx.isPureExpr(f.X) {
+				bindings = append(bindings, coq.Binding{
+					Names: []string{"$recv"}, Expr: l,
+				})
+				l = coq.IdentExpr("$recv")
+			}
+			bindings, args = ctx.delayedArgs(bindings, call, args)
+			args = append([]coq.Expr{l}, args...)
+			return bindings, coq.BlockExpr{Bindings: []coq.Binding{
+				coq.NewAnon(coq.NewCallExpr(method, args...)),
+			}}
+		}
+	}
+	fn, pure := ctx.delayedFuncValue(kw, call.Fun)
+	if !pure {
+		name := "$" + kw
+		bindings = append(bindings, coq.Binding{
+			Names: []string{name}, Expr: fn,
+		})
+		fn = coq.IdentExpr(name)
+	}
+	bindings, args = ctx.delayedArgs(bindings, call, args)
+	return bindings, coq.BlockExpr{Bindings: []coq.Binding{
+		coq.NewAnon(coq.NewCallValueExpr(fn, args...)),
+	}}
+}
+
+// delayedArgs binds the arguments to a delayed call that are not pure
+func (ctx Ctx) delayedArgs(bindings []coq.Binding, call *ast.CallExpr,
+	args []coq.Expr) ([]coq.Binding, []coq.Expr) {
+	// a variadic call has a packed slice as its last argument
+	packed := len(args) != len(call.Args) ||
+		len(args) > 0 && call.Ellipsis == token.NoPos &&
+			ctx.typeOf(call.Fun).Underlying().(*types.Signature).Variadic()
+	for i := range args {
+		if !(packed && i == len(args)-1) && ctx.isPureExpr(call.Args[i]) {
+			continue
+		}
+		name := fmt.Sprintf("$a%d", i)
+		bindings = append(bindings, coq.Binding{
+			Names: []string{name}, Expr: args[i],
+		})
+		args[i] = coq.IdentExpr(name)
+	}
+	return bindings, args
+}
+
+// delayedScope binds the names from delayedCall around e, in a nested scope
+func delayedScope(bindings []coq.Binding, e coq.Expr) coq.Expr {
+	for i := len(bindings) - 1; i >= 0; i-- {
+		e = coq.LetExpr{
+			Name: bindings[i].Names[0],
+			Val:  bindings[i].Expr,
+			Body: e,
+		}
+	}
+	return e
+}
+
+// goStmt translates a go statement, which runs the call in a new thread
+func (ctx Ctx) goStmt(e *ast.GoStmt) coq.Expr {
+	bindings, call := ctx.delayedCall("go", e.Call)
+	return delayedScope(bindings, coq.SpawnExpr{Body: call})
+}
+
+// deferStmt translates a defer statement, which pushes the call onto the
+// function's deferred calls (see funcBody)
+func (ctx Ctx) deferStmt(e *ast.DeferStmt) coq.Expr {
+	bindings, call := ctx.delayedCall("defer", e.Call)
+	return delayedScope(bindings, coq.NewCallExpr("defer",
+		coq.IdentExpr(coq.DeferStack), coq.FuncLit{Body: call}))
 }
 
 func (ctx Ctx) branchStmt(s *ast.BranchStmt) coq.Expr {
@@ -1859,14 +2888,6 @@ func (ctx Ctx) branchStmt(s *ast.BranchStmt) coq.Expr {
 	return nil
 }
 
-// getSpawn returns a non-nil spawned thread if the expression is a go call
-func (ctx Ctx) goStmt(e *ast.GoStmt) coq.Expr {
-	if len(e.Call.Args) > 0 {
-		ctx.unsupported(e, "go statement with parameters")
-	}
-	return ctx.spawnExpr(e.Call.Fun)
-}
-
 func (ctx Ctx) stmt(s ast.Stmt, c *cursor, loopVar *string) coq.Binding {
 	switch s := s.(type) {
 	case *ast.ReturnStmt:
@@ -1886,6 +2907,8 @@ func (ctx Ctx) stmt(s ast.Stmt, c *cursor, loopVar *string) coq.Binding {
 		return coq.NewAnon(ctx.branchStmt(s))
 	case *ast.GoStmt:
 		return coq.NewAnon(ctx.goStmt(s))
+	case *ast.DeferStmt:
+		return coq.NewAnon(ctx.deferStmt(s))
 	case *ast.ExprStmt:
 		return coq.NewAnon(ctx.expr(s.X))
 	case *ast.AssignStmt:
@@ -1916,8 +2939,12 @@ func (ctx Ctx) returnExpr(es []ast.Expr) coq.Expr {
 		return coq.ReturnExpr{coq.UnitLiteral{}}
 	}
 	var exprs coq.TupleExpr
-	for _, r := range es {
-		exprs = append(exprs, ctx.expr(r))
+	for i, r := range es {
+		if ctx.results != nil && ctx.results.Len() == len(es) {
+			exprs = append(exprs, ctx.exprAs(r, ctx.results.At(i).Type()))
+		} else {
+			exprs = append(exprs, ctx.expr(r))
+		}
 	}
 	return coq.ReturnExpr{coq.NewTuple(exprs)}
 }
@@ -1946,6 +2973,14 @@ func (ctx Ctx) returnType(results *ast.FieldList) coq.Type {
 }
 
 func (ctx Ctx) funcDecl(d *ast.FuncDecl) coq.FuncDecl {
+	fd := ctx.funcSignature(d)
+	ctx.results = ctx.info.Defs[d.Name].Type().(*types.Signature).Results()
+	fd.Body = ctx.funcBody(d.Type, d.Body)
+	return fd
+}
+
+// funcSignature translates everything about a function other than its body
+func (ctx Ctx) funcSignature(d *ast.FuncDecl) coq.FuncDecl {
 	fd := coq.FuncDecl{Name: d.Name.Name, AddTypes: ctx.Config.TypeCheck}
 	addSourceDoc(d.Doc, &fd.Comment)
 	ctx.addSourceFile(d, &fd.Comment)
@@ -1960,29 +2995,57 @@ func (ctx Ctx) funcDecl(d *ast.FuncDecl) coq.FuncDecl {
 			recvType = pT.Elem()
 		}
 
-		structInfo, ok := ctx.getStructInfo(recvType)
-		if !ok {
-			ctx.unsupported(d.Recv, "receiver does not appear to be a struct")
+		if structInfo, ok := ctx.getStructInfo(recvType); ok {
+			fd.Name = coq.TypeMethod(structInfo.name, d.Name.Name)
+		} else if named, ok := namedReceiver(recvType); ok {
+			fd.Name = coq.TypeMethod(ctx.qualifiedName(named.Obj()), d.Name.Name)
+		} else {
+			ctx.unsupported(d.Recv, "receiver does not appear to be a named type")
 		}
-		fd.Name = coq.StructMethod(structInfo.name, d.Name.Name)
 		fd.Args = append(fd.Args, ctx.field(receiver))
 	}
 	fd.Args = append(fd.Args, ctx.paramList(d.Type.Params)...)
 	fd.ReturnType = ctx.returnType(d.Type.Results)
-	fd.Body = ctx.blockStmt(d.Body, nil)
 	return fd
 }
 
+// hasDefer reports whether a function body has a defer statement (outside of
+// any nested function literals)
+func hasDefer(body *ast.BlockStmt) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.DeferStmt:
+			found = true
+		case *ast.FuncLit:
+			return false
+		}
+		return !found
+	})
+	return found
+}
+
+// funcBody translates the body of a function
+//
+// A function with defer statements runs its body with a stack of deferred
+// calls. If a deferred call recovers from a panic, the function returns the
+// zero value of its return type (named results are not supported).
+func (ctx Ctx) funcBody(t *ast.FuncType, body *ast.BlockStmt) coq.Expr {
+	if !hasDefer(body) {
+		return ctx.blockStmt(body, nil)
+	}
+	return coq.WithDeferExpr{
+		Zero: coq.NewCallExpr("zero_val", ctx.returnType(t.Results)),
+		Body: ctx.blockStmt(body, nil),
+	}
+}
+
 func (ctx Ctx) constSpec(spec *ast.ValueSpec) coq.ConstDecl {
 	ident := spec.Names[0]
 	cd := coq.ConstDecl{
 		Name:     ident.Name,
 		AddTypes: ctx.Config.TypeCheck,
 	}
-	ctx.addDef(ident, identInfo{
-		IsPtrWrapped: false,
-		IsMacro:      true,
-	})
 	addSourceDoc(spec.Comment, &cd.Comment)
 	val := spec.Values[0]
 	cd.Val = ctx.expr(val)
@@ -2003,6 +3066,41 @@ func (ctx Ctx) constDecl(d *ast.GenDecl) []coq.Decl {
 	return specs
 }
 
+// isSentinelError recognizes a package-level error such as
+//
+//	var ErrNotFound = errors.New("not found")
+func (ctx Ctx) isSentinelError(d *ast.ValueSpec) bool {
+	if len(d.Names) != 1 || len(d.Values) != 1 ||
+		!isErrorType(ctx.typeOf(d.Names[0])) {
+		return false
+	}
+	call, ok := d.Values[0].(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	f, ok := call.Fun.(*ast.SelectorExpr)
+	return ok && ctx.isPkg(f.X, "errors") && f.Sel.Name == "New"
+}
+
+// sentinelError translates a package-level error to a fixed value
+//
+// Go compares errors by identity, so the error cannot be a call to errors.New
+// at each use. Instead the value is tagged with the variable's name (qualified
+// by its package name), which makes it equal only to itself.
+func (ctx Ctx) sentinelError(doc *ast.CommentGroup, d *ast.ValueSpec) coq.ValDecl {
+	msg := d.Values[0].(*ast.CallExpr).Args[0]
+	if ctx.info.Types[msg].Value == nil {
+		ctx.unsupported(msg, "package-level error with a non-constant message")
+	}
+	obj := ctx.info.Defs[d.Names[0]]
+	vd := coq.ValDecl{
+		Name: obj.Name(),
+		Val:  coq.StringLiteral{Value: obj.Pkg().Name() + "." + obj.Name()},
+	}
+	addSourceDoc(doc, &vd.Comment)
+	return vd
+}
+
 func (ctx Ctx) checkGlobalVar(d *ast.ValueSpec) {
 	ctx.futureWork(d, "global variables (might be used for objects)")
 }
@@ -2019,30 +3117,87 @@ func stringLitValue(lit *ast.BasicLit) string {
 }
 
 var builtinImports = map[string]bool{
-	"github.com/tchajed/goose/machine":         true,
-	"github.com/tchajed/goose/machine/disk":    true,
-	"github.com/tchajed/goose/machine/filesys": true,
-	"sync": true,
-	"log":  true,
-	"fmt":  true,
+	machinePkg:    true,
+	diskPkg:       true,
+	filesysPkg:    true,
+	"errors":      true,
+	"sync":        true,
+	"sync/atomic": true,
+	"log":         true,
+	"fmt":         true,
 }
 
 func (ctx Ctx) imports(d []ast.Spec) []coq.Decl {
 	var decls []coq.Decl
 	for _, s := range d {
 		s := s.(*ast.ImportSpec)
+		importPath := stringLitValue(s.Path)
+		ctx.checkImportFFI(s, importPath)
 		if s.Name != nil {
-			ctx.unsupported(s, "renaming imports")
+			switch s.Name.Name {
+			case "_":
+				// nothing from the package is used
+				continue
+			case ".":
+				// references to the package are resolved by the type checker
+				// (see qualifiedName), but the special support for builtin
+				// packages only handles qualified references
+				if ctx.isBuiltinImport(importPath) {
+					ctx.unsupported(s, "dot import of %s", importPath)
+				}
+			}
 		}
-		importPath := stringLitValue(s.Path)
-		if !builtinImports[importPath] {
-			decls = append(decls, coq.ImportDecl{importPath})
+		if !ctx.isBuiltinImport(importPath) {
+			decls = append(decls, coq.ImportDecl{Path: importPath})
 		}
 	}
 	return decls
 }
 
+// addTopLevelDefs records how the names defined by a top-level declaration
+// are translated
+//
+// This is done for all declarations before translating any of them, since
+// declarations can refer to later ones.
+func (ctx Ctx) addTopLevelDefs(decl ast.Decl) {
+	d, ok := decl.(*ast.GenDecl)
+	if !ok {
+		return
+	}
+	for _, spec := range d.Specs {
+		switch spec := spec.(type) {
+		case *ast.TypeSpec:
+			_, isStruct := spec.Type.(*ast.StructType)
+			ctx.addDef(spec.Name, identInfo{
+				IsPtrWrapped: false,
+				IsMacro:      !isStruct,
+			})
+		case *ast.ValueSpec:
+			// constants and sentinel errors become Coq definitions (other
+			// global variables are unsupported)
+			if d.Tok == token.CONST || ctx.isSentinelError(spec) {
+				for _, name := range spec.Names {
+					ctx.addDef(name, identInfo{
+						IsPtrWrapped: false,
+						IsMacro:      true,
+					})
+				}
+			}
+		}
+	}
+}
+
 func (ctx Ctx) maybeDecls(d ast.Decl) []coq.Decl {
+	switch ctx.checkDirective(d) {
+	case directiveSkip:
+		return nil
+	case directiveAxiom:
+		return []coq.Decl{ctx.funcAxiom(d.(*ast.FuncDecl))}
+	case directiveOpaque:
+		fd := ctx.funcDecl(d.(*ast.FuncDecl))
+		fd.Opaque = true
+		return []coq.Decl{fd}
+	}
 	switch d := d.(type) {
 	case *ast.FuncDecl:
 		fd := ctx.funcDecl(d)
@@ -2058,6 +3213,9 @@ func (ctx Ctx) maybeDecls(d ast.Decl) []coq.Decl {
 				ctx.unsupported(d, "multiple vars")
 			}
 			spec := d.Specs[0].(*ast.ValueSpec)
+			if ctx.isSentinelError(spec) {
+				return []coq.Decl{ctx.sentinelError(d.Doc, spec)}
+			}
 			ctx.checkGlobalVar(spec)
 		case token.TYPE:
 			if len(d.Specs) > 1 {
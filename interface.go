@@ -11,13 +11,14 @@ import (
 	"strings"
 
 	"github.com/pkg/errors"
+	"golang.org/x/tools/go/packages"
 
 	"github.com/tchajed/goose/internal/coq"
 )
 
-// declsOrError translates one top-level declaration,
-// catching Goose translation errors and returning them as a regular Go error
-func (ctx Ctx) declsOrError(stmt ast.Decl) (decls []coq.Decl, err error) {
+// catchError runs f, catching Goose translation errors and returning them as
+// a regular Go error
+func catchError(f func()) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			if r, ok := r.(gooseError); ok {
@@ -27,7 +28,17 @@ func (ctx Ctx) declsOrError(stmt ast.Decl) (decls []coq.Decl, err error) {
 			}
 		}
 	}()
-	return ctx.maybeDecls(stmt), nil
+	f()
+	return nil
+}
+
+// declsOrError translates one top-level declaration,
+// catching Goose translation errors and returning them as a regular Go error
+func (ctx Ctx) declsOrError(stmt ast.Decl) (decls []coq.Decl, err error) {
+	err = catchError(func() {
+		decls = ctx.maybeDecls(stmt)
+	})
+	return
 }
 
 func filterImports(decls []coq.Decl) (nonImports []coq.Decl, imports coq.ImportDecls) {
@@ -43,26 +54,74 @@ func filterImports(decls []coq.Decl) (nonImports []coq.Decl, imports coq.ImportD
 }
 
 // Decls converts an entire package (possibly multiple files) to a list of decls
+//
+// The declarations are output in dependency order (see orderDecls), with a
+// comment naming the source file whenever the output moves to another file.
+// If any declarations have goose directives, the output starts with a
+// summary of them.
+//
+// With PartialTranslation, declarations that fail to translate are replaced
+// with axioms (and also listed in the summary); the errors are still returned.
 func (ctx Ctx) Decls(fs ...NamedFile) (decls []coq.Decl, errs []error) {
 	var imports coq.ImportDecls
 	for _, f := range fs {
-		if len(fs) > 1 {
-			decls = append(decls,
-				coq.NewComment(fmt.Sprintf("%s", f.Name())))
-		}
-		if f.Ast.Doc != nil {
-			decls = append(decls, coq.NewComment(f.Ast.Doc.Text()))
+		for _, d := range f.Ast.Decls {
+			ctx.addTopLevelDefs(d)
 		}
+	}
+	var units []declUnit
+	for i, f := range fs {
+		// a unit per file ensures its comments are output even if it has no
+		// other declarations
+		units = append(units, declUnit{file: i})
 		for _, d := range f.Ast.Decls {
 			newDecls, err := ctx.declsOrError(d)
+			failed := false
 			if err != nil {
 				errs = append(errs, err)
+				if ctx.PartialTranslation {
+					newDecls = ctx.failedDecls(d, err)
+					failed = true
+				}
 			}
 			newDecls, newImports := filterImports(newDecls)
-			decls = append(decls, newDecls...)
 			imports = append(imports, newImports...)
+			units = append(units, declUnit{
+				file:   i,
+				decl:   d,
+				decls:  newDecls,
+				name:   declName(d),
+				defs:   ctx.defines(d),
+				axiom:  failed || directive(d) == directiveAxiom,
+				failed: failed,
+			})
 		}
 	}
+
+	currentFile := -1
+	entered := make([]bool, len(fs))
+	enterFile := func(i int) {
+		if i == currentFile {
+			return
+		}
+		currentFile = i
+		f := fs[i]
+		if len(fs) > 1 {
+			decls = append(decls,
+				coq.NewComment(fmt.Sprintf("%s", f.Name())))
+		}
+		if f.Ast.Doc != nil && !entered[i] {
+			decls = append(decls, coq.NewComment(f.Ast.Doc.Text()))
+		}
+		entered[i] = true
+	}
+	decls = append(decls, ctx.directiveSummary(units)...)
+	ordered, orderErrs := ctx.orderDecls(units)
+	errs = append(errs, orderErrs...)
+	for _, u := range ordered {
+		enterFile(u.file)
+		decls = append(decls, u.decls...)
+	}
 	if len(imports) > 0 {
 		decls = append([]coq.Decl{imports}, decls...)
 	}
@@ -104,13 +163,16 @@ func sortedFiles(files map[string]*ast.File) []NamedFile {
 // Ast with all the declarations in the package.
 //
 // If the source directory has multiple source files, these are processed in
-// alphabetical order; this must be a topological sort of the definitions or the
-// Coq code will be out-of-order. Realistically files should not have
-// dependencies on each other, although sorting ensures the results are stable
-// and not dependent on map or directory iteration order.
+// alphabetical order, which ensures the results are stable and not dependent on
+// map or directory iteration order. Declarations are then output in dependency
+// order, moving a declaration earlier (possibly from a later file) when
+// something before it refers to it.
 func (config Config) TranslatePackage(pkgPath string, srcDir string) (coq.File, error) {
-	// TODO: we might be able to replace this implementation with something
-	//  based on go/packages; see https://pkg.go.dev/golang.org/x/tools/go/packages?tab=doc#Package.
+	if err := config.CheckFFI(); err != nil {
+		return coq.File{}, err
+	}
+	// TODO: this implementation only handles a single directory and can't
+	//  resolve imports of other local packages; see TranslatePackages.
 	fset := token.NewFileSet()
 	filter := func(info os.FileInfo) bool {
 		if strings.HasSuffix(info.Name(), "_test.go") {
@@ -159,10 +221,90 @@ func (config Config) TranslatePackage(pkgPath string, srcDir string) (coq.File,
 			errors.Wrap(err, "code does not type check")
 	}
 
+	return ctx.translateFiles(pkgName, files)
+}
+
+// translateFiles translates the files of a type-checked package
+func (ctx Ctx) translateFiles(goPackage string, files []NamedFile) (coq.File, error) {
 	decls, errs := ctx.Decls(files...)
-	err = nil
+	var err error
 	if len(errs) != 0 {
 		err = errors.Wrap(MultipleErrors(errs), "conversion failed")
 	}
-	return coq.File{GoPackage: pkgName, Decls: decls}, err
+	return coq.File{
+		GoPackage: goPackage,
+		FFI:       ctx.FFIModule(),
+		Decls:     decls,
+	}, err
+}
+
+// TranslatePackages translates the packages matching pkgPatterns, which use
+// the same syntax as the go command (for example, ./... or an import path)
+// and are interpreted relative to modDir.
+//
+// The packages are loaded with go/packages, which respects go.mod, build
+// tags and vendoring, and are type-checked together along with their
+// dependencies. There is one Coq file per package, in dependency order (each
+// package comes after the packages it imports). If some packages fail to
+// translate, the returned files still include a partial translation of them.
+func (config Config) TranslatePackages(modDir string,
+	pkgPatterns ...string) (files []coq.File, err error) {
+	if err := config.CheckFFI(); err != nil {
+		return nil, err
+	}
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles |
+			packages.NeedCompiledGoFiles | packages.NeedImports |
+			packages.NeedDeps | packages.NeedTypes |
+			packages.NeedSyntax | packages.NeedTypesInfo,
+		Dir: modDir,
+	}
+	pkgs, err := packages.Load(cfg, pkgPatterns...)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load packages")
+	}
+	var loadErrs []error
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, err := range pkg.Errors {
+			loadErrs = append(loadErrs, err)
+		}
+	})
+	if len(loadErrs) != 0 {
+		return nil, errors.Wrap(MultipleErrors(loadErrs),
+			"code does not type check")
+	}
+
+	roots := make(map[*packages.Package]bool)
+	for _, pkg := range pkgs {
+		roots[pkg] = true
+	}
+	var errs []error
+	// visiting in post-order puts dependencies first
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if !roots[pkg] {
+			return
+		}
+		if _, ok := config.externalPackage(pkg.PkgPath); ok {
+			// external packages are not translated
+			return
+		}
+		var pkgFiles []NamedFile
+		for i, f := range pkg.Syntax {
+			pkgFiles = append(pkgFiles,
+				NamedFile{Path: pkg.CompiledGoFiles[i], Ast: f})
+		}
+		sort.Slice(pkgFiles, func(i, j int) bool {
+			return pkgFiles[i].Path < pkgFiles[j].Path
+		})
+		ctx := NewPkgCtx(pkg, config)
+		f, err := ctx.translateFiles(pkg.PkgPath, pkgFiles)
+		if err != nil {
+			errs = append(errs, errors.Wrap(err, pkg.PkgPath))
+		}
+		files = append(files, f)
+	})
+	if len(errs) != 0 {
+		err = MultipleErrors(errs)
+	}
+	return files, err
 }
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 func isWellBalanced(s string, lDelim string, rDelim string) bool {
@@ -202,13 +204,38 @@ func (t SliceType) Coq() string {
 	return fmt.Sprintf("slice.T %s", addParens(t.Value.Coq()))
 }
 
+// SliceLiteral allocates a new slice with some initial elements
+type SliceLiteral struct {
+	Elt  Type
+	Elts []Expr
+}
+
+func (sl SliceLiteral) Coq() string {
+	var elts []string
+	for _, e := range sl.Elts {
+		elts = append(elts, e.Coq())
+	}
+	return fmt.Sprintf("slice.literal %s [%s]",
+		addParens(sl.Elt.Coq()), strings.Join(elts, "; "))
+}
+
+// ArrowType is the type of a function value
+type ArrowType struct {
+	Arg Type
+	Ret Type
+}
+
+func (t ArrowType) Coq() string {
+	return NewCallExpr("arrowT", t.Arg, t.Ret).Coq()
+}
+
 type ArrayType struct {
 	Len uint64
 	Elt Type
 }
 
 func (t ArrayType) Coq() string {
-	return fmt.Sprintf("arrayT %s", t.Elt.Coq())
+	return fmt.Sprintf("arrayT %d %s", t.Len, addParens(t.Elt.Coq()))
 }
 
 type Expr interface {
@@ -263,7 +290,58 @@ func (e IdentExpr) Coq() string {
 type GallinaString string
 
 func (s GallinaString) Coq() string {
-	return quote(string(s))
+	return StringVal(string(s))
+}
+
+// printableByte reports whether a byte can appear literally in a Coq string
+//
+// Bytes of multi-byte UTF-8 characters are left alone, since Coq reads string
+// literals byte-by-byte. Control characters (including newlines, which the
+// pretty printer would indent) are never printed literally.
+func printableByte(b byte) bool {
+	return b >= ' ' && b != 0x7f
+}
+
+// firstEscape returns the index of the first byte in s that cannot be written
+// literally in a Coq string, or len(s) if there is none.
+func firstEscape(s string) int {
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return r == utf8.RuneError || r > unicode.MaxASCII && !unicode.IsPrint(r)
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	for j := 0; j < i; j++ {
+		if !printableByte(s[j]) {
+			return j
+		}
+	}
+	return i
+}
+
+// StringVal renders an arbitrary Go string (a sequence of bytes) as a Gallina
+// string
+//
+// Double quotes are escaped by doubling them, as in Coq. Bytes that cannot be
+// written literally are spliced in with String and a decimal ascii literal.
+func StringVal(s string) string {
+	if firstEscape(s) == len(s) {
+		return stringVal(s)
+	}
+	return fmt.Sprintf("(%s)%%string", stringVal(s))
+}
+
+func stringVal(s string) string {
+	i := firstEscape(s)
+	if i == len(s) {
+		return quote(strings.ReplaceAll(s, `"`, `""`))
+	}
+	rest := stringVal(s[i+1:])
+	escaped := fmt.Sprintf(`String "%03d"%%char %s`, s[i], addParens(rest))
+	if i == 0 {
+		return escaped
+	}
+	return stringVal(s[:i]) + " ++ " + escaped
 }
 
 // CallExpr includes primitives and references to other functions.
@@ -281,6 +359,12 @@ func NewCallExpr(name string, args ...Expr) CallExpr {
 	return CallExpr{MethodName: name, Args: args}
 }
 
+// NewCallValueExpr constructs a call to a function computed by an expression
+// (rather than one referenced by name).
+func NewCallValueExpr(f Expr, args ...Expr) CallExpr {
+	return NewCallExpr(addParens(f.Coq()), args...)
+}
+
 func (s CallExpr) Coq() string {
 	comps := []string{s.MethodName}
 	for _, a := range s.Args {
@@ -441,7 +525,10 @@ type StringLiteral struct {
 }
 
 func (l StringLiteral) Coq() string {
-	return fmt.Sprintf(`#(str"%s")`, l.Value)
+	if firstEscape(l.Value) == len(l.Value) {
+		return fmt.Sprintf(`#(str%s)`, StringVal(l.Value))
+	}
+	return fmt.Sprintf(`#(str %s)`, StringVal(l.Value))
 }
 
 type nullLiteral struct{}
@@ -682,7 +769,8 @@ var LoopContinue = GallinaIdent("Continue")
 var LoopBreak = GallinaIdent("Break")
 
 type ForLoopExpr struct {
-	Init Binding
+	// bindings before the loop, which are in scope only in the loop
+	Init []Binding
 	Cond Expr
 	Post Expr
 	// the body of the loop
@@ -691,11 +779,21 @@ type ForLoopExpr struct {
 
 func (e ForLoopExpr) Coq() string {
 	var pp buffer
-	e.Init.AddTo(&pp)
+	scoped := false
+	for _, b := range e.Init {
+		b.AddTo(&pp)
+		scoped = scoped || !b.isAnonymous()
+	}
 	pp.Add("(for: (λ: <>, %s); (λ: <>, %s) := λ: <>,", e.Cond.Coq(), e.Post.Coq())
 	pp.Indent(2)
 	pp.Add("%s)", e.Body.Coq())
-	return pp.Build()
+	if !scoped {
+		return pp.Build()
+	}
+	// the loop variables go out of scope after the loop
+	var outer buffer
+	outer.Block("(", "%s)", pp.Build())
+	return outer.Build()
 }
 
 type Binder *IdentExpr
@@ -726,6 +824,41 @@ func (e SliceLoopExpr) Coq() string {
 	return pp.Build()
 }
 
+// IntLoopExpr is a loop over the integers from 0 up to (but not including) N.
+type IntLoopExpr struct {
+	Key  Binder
+	Ty   Expr
+	N    Expr
+	Body BlockExpr
+}
+
+func (e IntLoopExpr) Coq() string {
+	var pp buffer
+	pp.Add("ForRange %s %s %s",
+		addParens(e.Ty.Coq()), binderToCoq(e.Key), addParens(e.N.Coq()))
+	pp.Indent(2)
+	pp.Add("%s", addParens(e.Body.Coq()))
+	return pp.Build()
+}
+
+// StringLoopExpr is a loop over the runes of a string.
+type StringLoopExpr struct {
+	Key  Binder
+	Val  Binder
+	Str  Expr
+	Body BlockExpr
+}
+
+func (e StringLoopExpr) Coq() string {
+	var pp buffer
+	pp.Add("ForString %s %s %s",
+		binderToCoq(e.Key), binderToCoq(e.Val),
+		addParens(e.Str.Coq()))
+	pp.Indent(2)
+	pp.Add("%s", addParens(e.Body.Coq()))
+	return pp.Build()
+}
+
 // MapIterExpr is a call to the map iteration helper.
 type MapIterExpr struct {
 	// name of key and value identifiers
@@ -759,6 +892,52 @@ func (e SpawnExpr) Coq() string {
 	return pp.Build()
 }
 
+// DeferStack is the name of a function's stack of deferred calls
+const DeferStack = "$defers"
+
+// WithDeferExpr runs a function body that defers calls onto DeferStack.
+// The deferred calls run when the body returns or panics; Zero is returned
+// if one of them recovers from a panic.
+type WithDeferExpr struct {
+	Zero Expr
+	Body Expr
+}
+
+func (e WithDeferExpr) Coq() string {
+	var pp buffer
+	pp.Add("with_defer %s (λ: %s,", addParens(e.Zero.Coq()), quote(DeferStack))
+	pp.Indent(2)
+	pp.Add("%s)", e.Body.Coq())
+	return pp.Build()
+}
+
+// FuncLit is an anonymous function (a GooseLang lambda)
+type FuncLit struct {
+	Args []FieldDecl
+	Body Expr
+}
+
+func (e FuncLit) Coq() string {
+	var pp buffer
+	pp.Block("(λ: "+signature(e.Args)+", ", "%s)", e.Body.Coq())
+	return pp.Build()
+}
+
+// LetExpr binds a single name in an expression (as opposed to a Binding,
+// which is always part of a BlockExpr)
+type LetExpr struct {
+	Name string
+	Val  Expr
+	Body Expr
+}
+
+func (e LetExpr) Coq() string {
+	var pp buffer
+	pp.Block("(", "let: %s := %s in\n%s)",
+		binder(e.Name), e.Val.Coq(), e.Body.Coq())
+	return pp.Build()
+}
+
 // FuncDecl declares a function, including its parameters and body.
 type FuncDecl struct {
 	Name       string
@@ -767,12 +946,14 @@ type FuncDecl struct {
 	Body       Expr
 	Comment    string
 	AddTypes   bool
+	// Opaque makes the definition opaque (after its typing theorem), so
+	// proofs can only use the function through its specification
+	Opaque bool
 }
 
-// Signature renders the function declaration's bindings
-func (d FuncDecl) Signature() string {
+func signature(fields []FieldDecl) string {
 	var args []string
-	for _, a := range d.Args {
+	for _, a := range fields {
 		args = append(args, a.CoqBinder())
 	}
 	if len(args) == 0 {
@@ -781,6 +962,11 @@ func (d FuncDecl) Signature() string {
 	return strings.Join(args, " ")
 }
 
+// Signature renders the function declaration's bindings
+func (d FuncDecl) Signature() string {
+	return signature(d.Args)
+}
+
 func (d FuncDecl) Type() string {
 	types := []string{}
 	for _, a := range d.Args {
@@ -814,6 +1000,148 @@ func (d FuncDecl) CoqDecl() string {
 		pp.AddLine("Proof. typecheck. Qed.")
 		pp.Add("Hint Resolve %s_t : types.", d.Name)
 	}
+	if d.Opaque {
+		pp.Add("Global Opaque %s.", d.Name)
+	}
+	return pp.Build()
+}
+
+// AxiomDecl declares a function without defining it, for trusted code that
+// is not translated (the body of Func is ignored)
+type AxiomDecl struct {
+	Func FuncDecl
+}
+
+// CoqDecl implements the Decl interface
+//
+// For AxiomDecl this emits a Coq Axiom for the function, along with its type
+// if AddTypes is set.
+func (d AxiomDecl) CoqDecl() string {
+	var pp buffer
+	pp.AddComment(d.Func.Comment)
+	pp.Add("Axiom %s: val.", d.Func.Name)
+	if d.Func.AddTypes {
+		pp.Add("Axiom %s_t: ⊢ %s : (%s).", d.Func.Name, d.Func.Name, d.Func.Type())
+		pp.Add("Hint Resolve %s_t : types.", d.Func.Name)
+	}
+	return pp.Build()
+}
+
+// TypeAxiomDecl declares a type without defining it (for a struct, as a
+// module with an axiomatized descriptor, so it is used like other structs)
+type TypeAxiomDecl struct {
+	Name    string
+	Struct  bool
+	Comment string
+}
+
+// CoqDecl implements the Decl interface
+func (d TypeAxiomDecl) CoqDecl() string {
+	var pp buffer
+	pp.AddComment(d.Comment)
+	if !d.Struct {
+		pp.Add("Axiom %s: ty.", d.Name)
+		return pp.Build()
+	}
+	pp.Add("Module %s.", d.Name)
+	pp.Indent(2)
+	pp.AddLine("Axiom S: descriptor.")
+	pp.Indent(-2)
+	pp.Add("End %s.", d.Name)
+	return pp.Build()
+}
+
+// ConstAxiomDecl declares a constant without defining it (Type is optional,
+// and is only needed if AddTypes is set)
+type ConstAxiomDecl struct {
+	Name     string
+	Type     Type
+	Comment  string
+	AddTypes bool
+}
+
+// CoqDecl implements the Decl interface
+func (d ConstAxiomDecl) CoqDecl() string {
+	var pp buffer
+	pp.AddComment(d.Comment)
+	pp.Add("Axiom %s : expr.", d.Name)
+	if d.AddTypes {
+		pp.Add("Axiom %s_t Γ : Γ ⊢ %s : %s.",
+			d.Name, d.Name, addParens(d.Type.Coq()))
+	}
+	return pp.Build()
+}
+
+// MutualFuncDecl is a group of mutually recursive functions
+//
+// GooseLang only has singly recursive functions, so the group is defined by a
+// bundle Name, which takes the index of a function and returns that function.
+// Each function is then defined as a projection out of the bundle under its
+// original name. Within the bundle, the functions' names are bound (with
+// Gallina lets) to calls through the bundle, so the bodies refer to each other
+// (and themselves) as usual.
+type MutualFuncDecl struct {
+	Name  string
+	Funcs []FuncDecl
+}
+
+// bundleCall gives a function literal that calls function index of bundle f with
+// the arguments of d
+func (d FuncDecl) bundleCall(f Expr, index int) FuncLit {
+	args := []Expr{IntLiteral{uint64(index)}}
+	for _, a := range d.Args {
+		if a.Name == "_" {
+			args = append(args, Tt)
+		} else {
+			args = append(args, IdentExpr(a.Name))
+		}
+	}
+	if len(d.Args) == 0 {
+		args = append(args, Tt)
+	}
+	return FuncLit{Args: d.Args, Body: NewCallValueExpr(f, args...)}
+}
+
+// CoqDecl implements the Decl interface
+//
+// For MutualFuncDecl this emits the bundle followed by the definition of each
+// function.
+func (d MutualFuncDecl) CoqDecl() string {
+	var pp buffer
+	var names []string
+	for _, f := range d.Funcs {
+		names = append(names, f.Name)
+	}
+	pp.AddComment(fmt.Sprintf("mutually recursive functions %s",
+		strings.Join(names, ", ")))
+	pp.Add("Definition %s: val :=", d.Name)
+	func() {
+		pp.Indent(2)
+		defer pp.Indent(-2)
+		pp.Add("rec: %s %s :=", quote(d.Name), quote("$fn"))
+		pp.Indent(2)
+		defer pp.Indent(-2)
+		for i, f := range d.Funcs {
+			pp.Add("let %s := %s%%E in",
+				f.Name, f.bundleCall(IdentExpr(d.Name), i).Coq())
+		}
+		var body Expr = FuncLit{Args: d.Funcs[len(d.Funcs)-1].Args,
+			Body: d.Funcs[len(d.Funcs)-1].Body}
+		for i := len(d.Funcs) - 2; i >= 0; i-- {
+			body = IfExpr{
+				Cond: BinaryExpr{IdentExpr("$fn"), OpEquals, IntLiteral{uint64(i)}},
+				Then: FuncLit{Args: d.Funcs[i].Args, Body: d.Funcs[i].Body},
+				Else: body,
+			}
+		}
+		pp.AddLine(body.Coq() + ".")
+	}()
+	for i, f := range d.Funcs {
+		pp.AddLine("")
+		pp.AddComment(f.Comment)
+		pp.Add("Definition %s: val := %s.",
+			f.Name, f.bundleCall(GallinaIdent(d.Name), i).Coq())
+	}
 	return pp.Build()
 }
 
@@ -859,7 +1187,22 @@ func (d ConstDecl) CoqDecl() string {
 	return pp.Build()
 }
 
-// Decl is a FuncDecl, StructDecl, CommentDecl, or ConstDecl
+// ValDecl defines a GooseLang value, which (unlike the expression of a
+// ConstDecl) is the same at every use
+type ValDecl struct {
+	Name    string
+	Val     Expr
+	Comment string
+}
+
+func (d ValDecl) CoqDecl() string {
+	var pp buffer
+	pp.AddComment(d.Comment)
+	pp.Add("Definition %s: val := %s.", d.Name, d.Val.Coq())
+	return pp.Build()
+}
+
+// Decl is a FuncDecl, StructDecl, CommentDecl, ConstDecl, or ValDecl
 type Decl interface {
 	CoqDecl() string
 }
@@ -890,16 +1233,31 @@ func (t PtrType) Coq() string {
 	return NewCallExpr("refT", t.Value).Coq()
 }
 
-func StructMethod(structName string, methodName string) string {
-	return fmt.Sprintf("%s__%s", structName, methodName)
+// TypeMethod is the name of a method on a named type (struct or otherwise)
+func TypeMethod(typeName string, methodName string) string {
+	return fmt.Sprintf("%s__%s", typeName, methodName)
+}
+
+// ImportHeader gives the imports at the start of a translated file, for an
+// FFI given by the Coq module with its prelude (empty for no FFI)
+func ImportHeader(ffi string) string {
+	lines := []string{"From Perennial.goose_lang Require Import prelude."}
+	if ffi != "" {
+		lines = append(lines, requireImport(ffi))
+	}
+	return strings.Join(lines, "\n")
 }
 
-// TODO: note that the second two lines should be customized depending on the
-//  target interface.
-const importHeader string = `
-From Perennial.goose_lang Require Import prelude.
-From Perennial.goose_lang Require Import ffi.disk_prelude.
-`
+// requireImport imports a Coq module, relative to goose_lang if the module
+// is part of Perennial's GooseLang
+func requireImport(module string) string {
+	const gooseLang = "Perennial.goose_lang."
+	if strings.HasPrefix(module, gooseLang) {
+		return fmt.Sprintf("From Perennial.goose_lang Require Import %s.",
+			strings.TrimPrefix(module, gooseLang))
+	}
+	return fmt.Sprintf("Require Import %s.", module)
+}
 
 type ImportDecl struct {
 	Path string
@@ -919,10 +1277,19 @@ func ImportToPath(importPath string) string {
 	return filepath.Join(p, filename)
 }
 
+// ImportToModule gives the name of the Coq module for a Go import path
+//
+// This is the base name of the file given by ImportToPath, which is how
+// declarations from an imported package are qualified. It need not match the
+// Go package name.
+func ImportToModule(importPath string) string {
+	return path.Base(pathToCoqPath(importPath))
+}
+
 func (decl ImportDecl) CoqDecl() string {
 	coqPath := pathToCoqPath(decl.Path)
 	coqImportPath := strings.ReplaceAll(path.Dir(coqPath), "/", ".")
-	name := path.Base(decl.Path)
+	name := ImportToModule(decl.Path)
 	return fmt.Sprintf("From Goose Require %s.%s.", coqImportPath, name)
 }
 
@@ -947,7 +1314,9 @@ func (decls ImportDecls) CoqDecl() string {
 // File represents a complete Coq file (a sequence of declarations).
 type File struct {
 	GoPackage string
-	Decls     []Decl
+	// FFI is the Coq module with the prelude for the file's FFI
+	FFI   string
+	Decls []Decl
 }
 
 func (f File) autogeneratedNotice() CommentDecl {
@@ -959,7 +1328,7 @@ func (f File) autogeneratedNotice() CommentDecl {
 //noinspection GoUnhandledErrorResult
 func (f File) Write(w io.Writer) {
 	fmt.Fprintln(w, f.autogeneratedNotice().CoqDecl())
-	fmt.Fprintln(w, strings.Trim(importHeader, "\n"))
+	fmt.Fprintln(w, ImportHeader(f.FFI))
 	fmt.Fprintln(w)
 	for i, d := range f.Decls {
 		fmt.Fprintln(w, d.CoqDecl())
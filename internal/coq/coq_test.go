@@ -26,3 +26,39 @@ func TestPp(t *testing.T) {
      comment *)
 final line`, pp.Build())
 }
+
+func TestStringVal(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(`"foo"`, StringVal("foo"))
+	assert.Equal(`"say ""hi"""`, StringVal(`say "hi"`))
+	assert.Equal(`"héllo"`, StringVal("héllo"))
+	assert.Equal(`(String "010"%char "")%string`, StringVal("\n"))
+	assert.Equal(`("a" ++ String "000"%char (String "255"%char "b"))%string`,
+		StringVal("a\x00\xffb"))
+}
+
+func TestImportPaths(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("github_com/tchajed/marshal.v",
+		ImportToPath("github.com/tchajed/marshal"))
+	assert.Equal("marshal", ImportToModule("github.com/tchajed/marshal"))
+	assert.Equal("example_org/go_lib.v", ImportToPath("example.org/go-lib"))
+	assert.Equal("go_lib", ImportToModule("example.org/go-lib"))
+	assert.Equal("From Goose Require example_org.go_lib.",
+		ImportDecl{"example.org/go-lib"}.CoqDecl())
+}
+
+func TestAxiomDecl(t *testing.T) {
+	assert := assert.New(t)
+	f := FuncDecl{
+		Name:       "checksum",
+		Args:       []FieldDecl{{Name: "data", Type: SliceType{TypeIdent("byteT")}}},
+		ReturnType: TypeIdent("uint64T"),
+		AddTypes:   true,
+	}
+	assert.Equal("Axiom checksum: val.",
+		AxiomDecl{Func: FuncDecl{Name: "checksum"}}.CoqDecl())
+	assert.Equal(`Axiom checksum: val.
+Axiom checksum_t: ⊢ checksum : (slice.T byteT -> uint64T).
+Hint Resolve checksum_t : types.`, AxiomDecl{Func: f}.CoqDecl())
+}
@@ -0,0 +1,38 @@
+// Package external uses a library that goose translates through the external
+// packages declared in external.yaml.
+package external
+
+import "github.com/tchajed/goose/internal/examples/external/trusted"
+
+type writer struct {
+	buf *trusted.Buf
+}
+
+func newWriter() *writer {
+	trusted.DPrintf(1, "new writer\n")
+	return &writer{buf: trusted.NewBuf()}
+}
+
+func (w *writer) write(x byte) uint64 {
+	w.buf.Append(x)
+	return trusted.RoundUp(w.buf.Len(), 4096)
+}
+
+func spawnWrites(w *writer) {
+	go w.write(0)
+	go trusted.NewBuf()
+}
+
+func (w *writer) lenFunc() func() uint64 {
+	return w.buf.Len
+}
+
+func bufLen(b *trusted.Buf) uint64 {
+	f := (*trusted.Buf).Len
+	return f(b) + (*trusted.Buf).Len(b)
+}
+
+func (w *writer) appendLater(x byte) {
+	defer w.buf.Append(x)
+	go w.buf.Append(x)
+}
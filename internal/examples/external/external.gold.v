@@ -0,0 +1,48 @@
+(* autogenerated from external *)
+From Perennial.goose_lang Require Import prelude.
+From Perennial.goose_lang Require Import ffi.disk_prelude.
+
+(* Package external uses a library that goose translates through the external
+   packages declared in external.yaml. *)
+
+Module writer.
+  Definition S := struct.decl [
+    "buf" :: bufRefT
+  ].
+End writer.
+
+Definition newWriter: val :=
+  rec: "newWriter" <> :=
+    (* trusted.DPrintf(1, "new writer\n") *)
+    struct.new writer.S [
+      "buf" ::= buf.new #()
+    ].
+
+Definition writer__write: val :=
+  rec: "writer__write" "w" "x" :=
+    buf.append (struct.loadF writer.S "buf" "w") "x";;
+    util.roundUp (buf.len (struct.loadF writer.S "buf" "w")) #4096.
+
+Definition spawnWrites: val :=
+  rec: "spawnWrites" "w" :=
+    (let: "$go" := writer__write "w" in
+     Fork ("$go" (#(U8 0))));;
+    Fork (buf.new #()).
+
+Definition writer__lenFunc: val :=
+  rec: "writer__lenFunc" "w" :=
+    (let: "$recv" := struct.loadF writer.S "buf" "w" in
+     (λ: <>, buf.len "$recv")).
+
+Definition bufLen: val :=
+  rec: "bufLen" "b" :=
+    let: "f" := buf.len in
+    "f" "b" + buf.len "b".
+
+Definition writer__appendLater: val :=
+  rec: "writer__appendLater" "w" "x" :=
+    with_defer (zero_val unitT) (λ: "$defers",
+      (let: "$defer" := buf.append (struct.loadF writer.S "buf" "w") in
+       defer "$defers" (λ: <>, "$defer" "x"));;
+      (let: "$go" := buf.append (struct.loadF writer.S "buf" "w") in
+       Fork ("$go" "x"))).
@@ -0,0 +1,34 @@
+// Package trusted is a library that the external example declares as an
+// external package (see external.yaml), as if it were implemented directly in
+// GooseLang.
+package trusted
+
+import "log"
+
+const Debug uint64 = 0
+
+func DPrintf(level uint64, format string, a ...interface{}) {
+	if level <= Debug {
+		log.Printf(format, a...)
+	}
+}
+
+type Buf struct {
+	data []byte
+}
+
+func NewBuf() *Buf {
+	return &Buf{}
+}
+
+func (b *Buf) Append(x byte) {
+	b.data = append(b.data, x)
+}
+
+func (b *Buf) Len() uint64 {
+	return uint64(len(b.data))
+}
+
+func RoundUp(n uint64, sz uint64) uint64 {
+	return (n + sz - 1) / sz * sz
+}
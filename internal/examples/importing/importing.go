@@ -0,0 +1,61 @@
+// Package importing uses struct types, methods, constants and aliases from
+// another goose package.
+package importing
+
+import "github.com/tchajed/goose/internal/examples/shared"
+
+type table struct {
+	entries []*shared.Entry
+	nextId  shared.Id
+	ts      shared.Timestamp
+}
+
+func newTable() *table {
+	return &table{
+		entries: make([]*shared.Entry, 0, shared.MaxEntries),
+		nextId:  shared.Id(0),
+		ts:      0,
+	}
+}
+
+func (t *table) insert(key uint64, value []byte) shared.Id {
+	e := shared.NewEntry(key)
+	e.Value = value
+	e.Ts = t.ts
+	t.entries = append(t.entries, e)
+	id := t.nextId
+	t.nextId = id.Next()
+	return id
+}
+
+func (t *table) full() bool {
+	return uint64(len(t.entries)) >= shared.MaxEntries
+}
+
+func totalSize(entries []*shared.Entry) uint64 {
+	var total = uint64(0)
+	for _, e := range entries {
+		total = total + e.Size()
+	}
+	return total
+}
+
+func firstNonEmpty(entries []*shared.Entry) uint64 {
+	var key = uint64(0)
+	var found = false
+	for _, e := range entries {
+		if !found && e.Size() != 0 {
+			key = e.Key
+			found = true
+		}
+	}
+	return key
+}
+
+func isEmpty(e shared.Entry) bool {
+	return e.IsEmpty()
+}
+
+func literalEntry(ts shared.Timestamp) shared.Entry {
+	return shared.Entry{Key: 0, Value: nil, Ts: ts}
+}
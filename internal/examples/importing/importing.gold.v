@@ -0,0 +1,85 @@
+(* autogenerated from importing *)
+From Perennial.goose_lang Require Import prelude.
+From Perennial.goose_lang Require Import ffi.disk_prelude.
+
+From Goose Require github_com.tchajed.goose.internal.examples.shared.
+
+(* importing.go *)
+
+(* Package importing uses struct types, methods, constants and aliases from
+   another goose package. *)
+
+Module table.
+  Definition S := struct.decl [
+    "entries" :: slice.T (struct.ptrT shared.Entry.S);
+    "nextId" :: shared.Id;
+    "ts" :: shared.Timestamp
+  ].
+End table.
+
+Definition newTable: val :=
+  rec: "newTable" <> :=
+    struct.new table.S [
+      "entries" ::= NewSlice (struct.ptrT shared.Entry.S) #0;
+      "nextId" ::= #0;
+      "ts" ::= #0
+    ].
+
+Definition table__insert: val :=
+  rec: "table__insert" "t" "key" "value" :=
+    let: "e" := shared.NewEntry "key" in
+    struct.storeF shared.Entry.S "Value" "e" "value";;
+    struct.storeF shared.Entry.S "Ts" "e" (struct.loadF table.S "ts" "t");;
+    struct.storeF table.S "entries" "t" (SliceAppend (refT (struct.t shared.Entry.S)) (struct.loadF table.S "entries" "t") "e");;
+    let: "id" := struct.loadF table.S "nextId" "t" in
+    struct.storeF table.S "nextId" "t" (shared.Id__Next "id");;
+    "id".
+
+Definition table__full: val :=
+  rec: "table__full" "t" :=
+    slice.len (struct.loadF table.S "entries" "t") ≥ shared.MaxEntries.
+
+Definition totalSize: val :=
+  rec: "totalSize" "entries" :=
+    let: "total" := ref_to uint64T #0 in
+    ForSlice (refT (struct.t shared.Entry.S)) <> "e" "entries"
+      ("total" <-[uint64T] ![uint64T] "total" + shared.Entry__Size "e");;
+    ![uint64T] "total".
+
+Definition firstNonEmpty: val :=
+  rec: "firstNonEmpty" "entries" :=
+    let: "key" := ref_to uint64T #0 in
+    let: "found" := ref_to boolT #false in
+    ForSlice (refT (struct.t shared.Entry.S)) <> "e" "entries"
+      (if: (~ (![boolT] "found")) && (shared.Entry__Size "e" ≠ #0)
+      then
+        "key" <-[uint64T] struct.loadF shared.Entry.S "Key" "e";;
+        "found" <-[boolT] #true
+      else #());;
+    ![uint64T] "key".
+
+Definition isEmpty: val :=
+  rec: "isEmpty" "e" :=
+    shared.Entry__IsEmpty "e".
+
+Definition literalEntry: val :=
+  rec: "literalEntry" "ts" :=
+    struct.mk shared.Entry.S [
+      "Key" ::= #0;
+      "Value" ::= slice.nil;
+      "Ts" ::= "ts"
+    ].
+
+(* renamed.go *)
+
+Definition nextEntry: val :=
+  rec: "nextEntry" "id" :=
+    shared.NewEntry (shared.Id__Next "id").
+
+Definition maxEntry: val :=
+  rec: "maxEntry" <> :=
+    shared.NewEntry shared.MaxEntries.
+
+Definition entryTimestamp: val :=
+  rec: "entryTimestamp" "e" :=
+    struct.loadF shared.Entry.S "Ts" "e".
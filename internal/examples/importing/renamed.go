@@ -0,0 +1,18 @@
+package importing
+
+import (
+	. "github.com/tchajed/goose/internal/examples/shared"
+	sh "github.com/tchajed/goose/internal/examples/shared"
+)
+
+func nextEntry(id sh.Id) *sh.Entry {
+	return sh.NewEntry(uint64(id.Next()))
+}
+
+func maxEntry() *Entry {
+	return NewEntry(MaxEntries)
+}
+
+func entryTimestamp(e *Entry) Timestamp {
+	return e.Ts
+}
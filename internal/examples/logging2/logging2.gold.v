@@ -70,11 +70,11 @@ Hint Resolve Log__readHdr_t : types.
 Definition Log__readBlocks: val :=
   rec: "Log__readBlocks" "log" "len" :=
     let: "blks" := ref_to (slice.T (slice.T byteT)) (NewSlice disk.blockT #0) in
-    let: "i" := ref_to uint64T #0 in
-    (for: (λ: <>, ![uint64T] "i" < "len"); (λ: <>, "i" <-[uint64T] ![uint64T] "i" + #1) := λ: <>,
-      let: "blk" := disk.Read (LOGSTART + ![uint64T] "i") in
-      "blks" <-[slice.T (slice.T byteT)] SliceAppend (slice.T byteT) (![slice.T (slice.T byteT)] "blks") "blk";;
-      Continue);;
+    (let: "i" := ref_to uint64T #0 in
+     (for: (λ: <>, ![uint64T] "i" < "len"); (λ: <>, "i" <-[uint64T] ![uint64T] "i" + #1) := λ: <>,
+       let: "blk" := disk.Read (LOGSTART + ![uint64T] "i") in
+       "blks" <-[slice.T (slice.T byteT)] SliceAppend (slice.T byteT) (![slice.T (slice.T byteT)] "blks") "blk";;
+       Continue));;
     ![slice.T (slice.T byteT)] "blks".
 Theorem Log__readBlocks_t: ⊢ Log__readBlocks : (struct.t Log.S -> uint64T -> slice.T disk.blockT).
 Proof. typecheck. Qed.
@@ -94,10 +94,10 @@ Hint Resolve Log__Read_t : types.
 Definition Log__memWrite: val :=
   rec: "Log__memWrite" "log" "l" :=
     let: "n" := slice.len "l" in
-    let: "i" := ref_to uint64T #0 in
-    (for: (λ: <>, ![uint64T] "i" < "n"); (λ: <>, "i" <-[uint64T] ![uint64T] "i" + #1) := λ: <>,
-      struct.get Log.S "memLog" "log" <-[slice.T (slice.T byteT)] SliceAppend (slice.T byteT) (![slice.T (slice.T byteT)] (struct.get Log.S "memLog" "log")) (SliceGet (slice.T byteT) "l" (![uint64T] "i"));;
-      Continue).
+    (let: "i" := ref_to uint64T #0 in
+     (for: (λ: <>, ![uint64T] "i" < "n"); (λ: <>, "i" <-[uint64T] ![uint64T] "i" + #1) := λ: <>,
+       struct.get Log.S "memLog" "log" <-[slice.T (slice.T byteT)] SliceAppend (slice.T byteT) (![slice.T (slice.T byteT)] (struct.get Log.S "memLog" "log")) (SliceGet (slice.T byteT) "l" (![uint64T] "i"));;
+       Continue)).
 Theorem Log__memWrite_t: ⊢ Log__memWrite : (struct.t Log.S -> slice.T disk.blockT -> unitT).
 Proof. typecheck. Qed.
 Hint Resolve Log__memWrite_t : types.
@@ -159,11 +159,11 @@ Hint Resolve Log__Append_t : types.
 Definition Log__writeBlocks: val :=
   rec: "Log__writeBlocks" "log" "l" "pos" :=
     let: "n" := slice.len "l" in
-    let: "i" := ref_to uint64T #0 in
-    (for: (λ: <>, ![uint64T] "i" < "n"); (λ: <>, "i" <-[uint64T] ![uint64T] "i" + #1) := λ: <>,
-      let: "bk" := SliceGet (slice.T byteT) "l" (![uint64T] "i") in
-      disk.Write ("pos" + ![uint64T] "i") "bk";;
-      Continue).
+    (let: "i" := ref_to uint64T #0 in
+     (for: (λ: <>, ![uint64T] "i" < "n"); (λ: <>, "i" <-[uint64T] ![uint64T] "i" + #1) := λ: <>,
+       let: "bk" := SliceGet (slice.T byteT) "l" (![uint64T] "i") in
+       disk.Write ("pos" + ![uint64T] "i") "bk";;
+       Continue)).
 Theorem Log__writeBlocks_t: ⊢ Log__writeBlocks : (struct.t Log.S -> slice.T disk.blockT -> uint64T -> unitT).
 Proof. typecheck. Qed.
 Hint Resolve Log__writeBlocks_t : types.
@@ -0,0 +1,38 @@
+// Package partial has declarations that goose cannot translate, which a
+// partial translation replaces with axioms.
+package partial
+
+type signedCounter struct {
+	n int
+}
+
+type offset int
+
+const defaultOffset offset = -1
+
+func half(x uint64) uint64 {
+	var y int = int(x)
+	return uint64(y / 2)
+}
+
+func quarter(x uint64) uint64 {
+	return half(half(x))
+}
+
+type block struct {
+	data []byte
+}
+
+func (b *block) size() uint64 {
+	return uint64(len(b.data))
+}
+
+func (b *block) halfSize() uint64 {
+	return half(b.size())
+}
+
+func (o offset) addAll(xs []int) {}
+
+func addNone() {
+	defaultOffset.addAll(nil)
+}
@@ -0,0 +1,72 @@
+(* autogenerated from partial *)
+From Perennial.goose_lang Require Import prelude.
+From Perennial.goose_lang Require Import ffi.disk_prelude.
+
+(* failed declarations:
+   signedCounter (partial.go:5)
+   offset (partial.go:9)
+   defaultOffset (partial.go:11)
+   half (partial.go:13)
+   offset.addAll (partial.go:34) *)
+
+(* Package partial has declarations that goose cannot translate, which a
+   partial translation replaces with axioms. *)
+
+(* goose could not translate this declaration:
+   [unsupported]: basic type int (int) *)
+Module signedCounter.
+  Axiom S: descriptor.
+End signedCounter.
+
+(* goose could not translate this declaration:
+   [unsupported]: basic type int (int) *)
+Axiom offset: ty.
+
+(* goose could not translate this declaration:
+   [unsupported]: unary expression - (-1) *)
+Axiom defaultOffset : expr.
+Axiom defaultOffset_t Γ : Γ ⊢ defaultOffset : offset.
+
+(* goose could not translate this declaration:
+   [unsupported]: basic type int (y int = int(x)) *)
+Axiom half: val.
+Axiom half_t: ⊢ half : (uint64T -> uint64T).
+Hint Resolve half_t : types.
+
+Definition quarter: val :=
+  rec: "quarter" "x" :=
+    half (half "x").
+Theorem quarter_t: ⊢ quarter : (uint64T -> uint64T).
+Proof. typecheck. Qed.
+Hint Resolve quarter_t : types.
+
+Module block.
+  Definition S := struct.decl [
+    "data" :: slice.T byteT
+  ].
+End block.
+
+Definition block__size: val :=
+  rec: "block__size" "b" :=
+    slice.len (struct.loadF block.S "data" "b").
+Theorem block__size_t: ⊢ block__size : (struct.ptrT block.S -> uint64T).
+Proof. typecheck. Qed.
+Hint Resolve block__size_t : types.
+
+Definition block__halfSize: val :=
+  rec: "block__halfSize" "b" :=
+    half (block__size "b").
+Theorem block__halfSize_t: ⊢ block__halfSize : (struct.ptrT block.S -> uint64T).
+Proof. typecheck. Qed.
+Hint Resolve block__halfSize_t : types.
+
+(* goose could not translate this declaration:
+   [unsupported]: basic type int (int) *)
+Axiom offset__addAll: val.
+
+Definition addNone: val :=
+  rec: "addNone" <> :=
+    offset__addAll defaultOffset slice.nil.
+Theorem addNone_t: ⊢ addNone : (unitT -> unitT).
+Proof. typecheck. Qed.
+Hint Resolve addNone_t : types.
@@ -38,11 +38,11 @@ Definition Fileid3: ty := Uint64.
 
 Definition Cookie3: ty := Uint64.
 
-Definition Cookieverf3: ty := arrayT byteT.
+Definition Cookieverf3: ty := arrayT 8 byteT.
 
-Definition Createverf3: ty := arrayT byteT.
+Definition Createverf3: ty := arrayT 8 byteT.
 
-Definition Writeverf3: ty := arrayT byteT.
+Definition Writeverf3: ty := arrayT 8 byteT.
 
 Definition Uid3: ty := Uint32.
 
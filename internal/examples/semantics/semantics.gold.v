@@ -36,11 +36,11 @@ Hint Resolve allocate_t : types.
 Definition freeRange: val :=
   rec: "freeRange" "sz" :=
     let: "m" := NewMap (struct.t unit.S) in
-    let: "i" := ref_to uint64T #0 in
-    (for: (λ: <>, ![uint64T] "i" < "sz"); (λ: <>, "i" <-[uint64T] ![uint64T] "i" + #1) := λ: <>,
-      MapInsert "m" (![uint64T] "i") (struct.mk unit.S [
-      ]);;
-      Continue);;
+    (let: "i" := ref_to uint64T #0 in
+     (for: (λ: <>, ![uint64T] "i" < "sz"); (λ: <>, "i" <-[uint64T] ![uint64T] "i" + #1) := λ: <>,
+       MapInsert "m" (![uint64T] "i") (struct.mk unit.S [
+       ]);;
+       Continue));;
     "m".
 Theorem freeRange_t: ⊢ freeRange : (uint64T -> mapT (struct.t unit.S)).
 Proof. typecheck. Qed.
@@ -433,16 +433,16 @@ Hint Resolve testsUseLocks_t : types.
 Definition standardForLoop: val :=
   rec: "standardForLoop" "s" :=
     let: "sumPtr" := ref (zero_val uint64T) in
-    let: "i" := ref_to uint64T #0 in
-    (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
-      (if: ![uint64T] "i" < slice.len "s"
-      then
-        let: "sum" := ![uint64T] "sumPtr" in
-        let: "x" := SliceGet uint64T "s" (![uint64T] "i") in
-        "sumPtr" <-[uint64T] "sum" + "x";;
-        "i" <-[uint64T] ![uint64T] "i" + #1;;
-        Continue
-      else Break));;
+    (let: "i" := ref_to uint64T #0 in
+     (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
+       (if: ![uint64T] "i" < slice.len "s"
+       then
+         let: "sum" := ![uint64T] "sumPtr" in
+         let: "x" := SliceGet uint64T "s" (![uint64T] "i") in
+         "sumPtr" <-[uint64T] "sum" + "x";;
+         "i" <-[uint64T] ![uint64T] "i" + #1;;
+         Continue
+       else Break)));;
     let: "sum" := ![uint64T] "sumPtr" in
     "sum".
 Theorem standardForLoop_t: ⊢ standardForLoop : (slice.T uint64T -> uint64T).
@@ -574,19 +574,19 @@ Definition testNestedLoops: val :=
   rec: "testNestedLoops" <> :=
     let: "ok1" := ref_to boolT #false in
     let: "ok2" := ref_to boolT #false in
-    let: "i" := ref_to uint64T #0 in
-    (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
-      let: "j" := ref_to uint64T #0 in
-      (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
-        (if: ![uint64T] "j" > #5
-        then Break
-        else
-          "j" <-[uint64T] ![uint64T] "j" + #1;;
-          "ok1" <-[boolT] (![uint64T] "j" = #6);;
-          Continue));;
-      "i" <-[uint64T] ![uint64T] "i" + #1;;
-      "ok2" <-[boolT] (![uint64T] "i" = #1);;
-      Break);;
+    (let: "i" := ref_to uint64T #0 in
+     (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
+       (let: "j" := ref_to uint64T #0 in
+        (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
+          (if: ![uint64T] "j" > #5
+          then Break
+          else
+            "j" <-[uint64T] ![uint64T] "j" + #1;;
+            "ok1" <-[boolT] (![uint64T] "j" = #6);;
+            Continue)));;
+       "i" <-[uint64T] ![uint64T] "i" + #1;;
+       "ok2" <-[boolT] (![uint64T] "i" = #1);;
+       Break));;
     (![boolT] "ok1") && (![boolT] "ok2").
 Theorem testNestedLoops_t: ⊢ testNestedLoops : (unitT -> boolT).
 Proof. typecheck. Qed.
@@ -595,15 +595,15 @@ Hint Resolve testNestedLoops_t : types.
 Definition testNestedGoStyleLoops: val :=
   rec: "testNestedGoStyleLoops" <> :=
     let: "ok" := ref_to boolT #false in
-    let: "i" := ref_to uint64T #0 in
-    (for: (λ: <>, ![uint64T] "i" < #10); (λ: <>, "i" <-[uint64T] ![uint64T] "i" + #1) := λ: <>,
-      let: "j" := ref_to uint64T #0 in
-      (for: (λ: <>, ![uint64T] "j" < ![uint64T] "i"); (λ: <>, "j" <-[uint64T] ![uint64T] "j" + #1) := λ: <>,
-        (if: #true
-        then Break
-        else Continue));;
-      "ok" <-[boolT] (![uint64T] "i" = #9);;
-      Continue);;
+    (let: "i" := ref_to uint64T #0 in
+     (for: (λ: <>, ![uint64T] "i" < #10); (λ: <>, "i" <-[uint64T] ![uint64T] "i" + #1) := λ: <>,
+       (let: "j" := ref_to uint64T #0 in
+        (for: (λ: <>, ![uint64T] "j" < ![uint64T] "i"); (λ: <>, "j" <-[uint64T] ![uint64T] "j" + #1) := λ: <>,
+          (if: #true
+          then Break
+          else Continue)));;
+       "ok" <-[boolT] (![uint64T] "i" = #9);;
+       Continue));;
     ![boolT] "ok".
 Theorem testNestedGoStyleLoops_t: ⊢ testNestedGoStyleLoops : (unitT -> boolT).
 Proof. typecheck. Qed.
@@ -612,15 +612,15 @@ Hint Resolve testNestedGoStyleLoops_t : types.
 Definition testNestedGoStyleLoopsNoComparison: val :=
   rec: "testNestedGoStyleLoopsNoComparison" <> :=
     let: "ok" := ref_to boolT #false in
-    let: "i" := ref_to uint64T #0 in
-    (for: (λ: <>, ![uint64T] "i" < #10); (λ: <>, "i" <-[uint64T] ![uint64T] "i" + #1) := λ: <>,
-      let: "j" := ref_to uint64T #0 in
-      (for: (λ: <>, ![uint64T] "j" < ![uint64T] "i"); (λ: <>, "j" <-[uint64T] ![uint64T] "j" + #1) := λ: <>,
-        (if: #true
-        then Break
-        else Continue));;
-      "ok" <-[boolT] (![uint64T] "i" = #9);;
-      Continue);;
+    (let: "i" := ref_to uint64T #0 in
+     (for: (λ: <>, ![uint64T] "i" < #10); (λ: <>, "i" <-[uint64T] ![uint64T] "i" + #1) := λ: <>,
+       (let: "j" := ref_to uint64T #0 in
+        (for: (λ: <>, ![uint64T] "j" < ![uint64T] "i"); (λ: <>, "j" <-[uint64T] ![uint64T] "j" + #1) := λ: <>,
+          (if: #true
+          then Break
+          else Continue)));;
+       "ok" <-[boolT] (![uint64T] "i" = #9);;
+       Continue));;
     ![boolT] "ok".
 Theorem testNestedGoStyleLoopsNoComparison_t: ⊢ testNestedGoStyleLoopsNoComparison : (unitT -> boolT).
 Proof. typecheck. Qed.
@@ -1346,9 +1346,11 @@ Definition testStructConstructions: val :=
     ] in
     "ok" <-[boolT] (![boolT] "ok") && (![refT (struct.t TwoInts.S)] "p1" = #null);;
     "p1" <-[refT (struct.t TwoInts.S)] struct.alloc TwoInts.S (zero_val (struct.t TwoInts.S));;
-    "ok" <-[boolT] (![boolT] "ok") && (![struct.t TwoInts.S] "p2" = "p3");;
-    "ok" <-[boolT] (![boolT] "ok") && ("p3" = "p4");;
-    "ok" <-[boolT] (![boolT] "ok") && ("p4" = struct.load TwoInts.S (![refT (struct.t TwoInts.S)] "p1"));;
+    "ok" <-[boolT] (![boolT] "ok") && (let: "$x" := ![struct.t TwoInts.S] "p2" in
+     (struct.get TwoInts.S "x" "$x" = struct.get TwoInts.S "x" "p3") && (struct.get TwoInts.S "y" "$x" = struct.get TwoInts.S "y" "p3"));;
+    "ok" <-[boolT] (![boolT] "ok") && (struct.get TwoInts.S "x" "p3" = struct.get TwoInts.S "x" "p4") && (struct.get TwoInts.S "y" "p3" = struct.get TwoInts.S "y" "p4");;
+    "ok" <-[boolT] (![boolT] "ok") && (let: "$y" := struct.load TwoInts.S (![refT (struct.t TwoInts.S)] "p1") in
+     (struct.get TwoInts.S "x" "p4" = struct.get TwoInts.S "x" "$y") && (struct.get TwoInts.S "y" "p4" = struct.get TwoInts.S "y" "$y"));;
     "ok" <-[boolT] (![boolT] "ok") && ("p4" ≠ ![refT (struct.t TwoInts.S)] "p1");;
     ![boolT] "ok".
 Theorem testStructConstructions_t: ⊢ testStructConstructions : (unitT -> boolT).
@@ -1572,15 +1574,15 @@ Hint Resolve getLogEntry_t : types.
 (* applyLog assumes we are running sequentially *)
 Definition applyLog: val :=
   rec: "applyLog" "d" "length" :=
-    let: "i" := ref_to uint64T #0 in
-    (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
-      (if: ![uint64T] "i" < "length"
-      then
-        let: ("a", "v") := getLogEntry "d" (![uint64T] "i") in
-        disk.Write (logLength + "a") "v";;
-        "i" <-[uint64T] ![uint64T] "i" + #1;;
-        Continue
-      else Break)).
+    (let: "i" := ref_to uint64T #0 in
+     (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
+       (if: ![uint64T] "i" < "length"
+       then
+         let: ("a", "v") := getLogEntry "d" (![uint64T] "i") in
+         disk.Write (logLength + "a") "v";;
+         "i" <-[uint64T] ![uint64T] "i" + #1;;
+         Continue
+       else Break))).
 Theorem applyLog_t: ⊢ applyLog : (disk.Disk -> uint64T -> unitT).
 Proof. typecheck. Qed.
 Hint Resolve applyLog_t : types.
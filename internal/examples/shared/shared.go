@@ -0,0 +1,32 @@
+// Package shared is imported by the importing example, to check references to
+// declarations from another goose-translated package.
+package shared
+
+const MaxEntries uint64 = 16
+
+// Timestamp is an alias, which importers can use by name.
+type Timestamp = uint64
+
+type Id uint64
+
+func (id Id) Next() Id {
+	return id + 1
+}
+
+type Entry struct {
+	Key   uint64
+	Value []byte
+	Ts    Timestamp
+}
+
+func NewEntry(key uint64) *Entry {
+	return &Entry{Key: key, Value: nil, Ts: 0}
+}
+
+func (e *Entry) Size() uint64 {
+	return uint64(len(e.Value))
+}
+
+func (e Entry) IsEmpty() bool {
+	return len(e.Value) == 0
+}
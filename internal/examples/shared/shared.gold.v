@@ -0,0 +1,40 @@
+(* autogenerated from shared *)
+From Perennial.goose_lang Require Import prelude.
+From Perennial.goose_lang Require Import ffi.disk_prelude.
+
+(* Package shared is imported by the importing example, to check references to
+   declarations from another goose-translated package. *)
+
+Definition MaxEntries : expr := #16.
+
+Definition Timestamp: ty := uint64T.
+
+Definition Id: ty := uint64T.
+
+Definition Id__Next: val :=
+  rec: "Id__Next" "id" :=
+    "id" + #1.
+
+Module Entry.
+  Definition S := struct.decl [
+    "Key" :: uint64T;
+    "Value" :: slice.T byteT;
+    "Ts" :: Timestamp
+  ].
+End Entry.
+
+Definition NewEntry: val :=
+  rec: "NewEntry" "key" :=
+    struct.new Entry.S [
+      "Key" ::= "key";
+      "Value" ::= slice.nil;
+      "Ts" ::= #0
+    ].
+
+Definition Entry__Size: val :=
+  rec: "Entry__Size" "e" :=
+    slice.len (struct.loadF Entry.S "Value" "e").
+
+Definition Entry__IsEmpty: val :=
+  rec: "Entry__IsEmpty" "e" :=
+    (slice.len (struct.get Entry.S "Value" "e") = #0).
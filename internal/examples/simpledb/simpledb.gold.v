@@ -1,6 +1,6 @@
 (* autogenerated from simpledb *)
 From Perennial.goose_lang Require Import prelude.
-From Perennial.goose_lang Require Import ffi.disk_prelude.
+From Perennial.goose_lang Require Import ffi.filesys_prelude.
 
 (* Package simpledb implements a one-table version of LevelDB
 
@@ -94,32 +94,32 @@ End lazyFileBuf.
 (* readTableIndex parses a complete table on disk into a key->offset index *)
 Definition readTableIndex: val :=
   rec: "readTableIndex" "f" "index" :=
-    let: "buf" := ref_to (struct.t lazyFileBuf.S) (struct.mk lazyFileBuf.S [
-      "offset" ::= #0;
-      "next" ::= slice.nil
-    ]) in
-    (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
-      let: ("e", "l") := DecodeEntry (struct.get lazyFileBuf.S "next" (![struct.t lazyFileBuf.S] "buf")) in
-      (if: "l" > #0
-      then
-        MapInsert "index" (struct.get Entry.S "Key" "e") (#8 + struct.get lazyFileBuf.S "offset" (![struct.t lazyFileBuf.S] "buf"));;
-        "buf" <-[struct.t lazyFileBuf.S] struct.mk lazyFileBuf.S [
-          "offset" ::= struct.get lazyFileBuf.S "offset" (![struct.t lazyFileBuf.S] "buf") + "l";
-          "next" ::= SliceSkip byteT (struct.get lazyFileBuf.S "next" (![struct.t lazyFileBuf.S] "buf")) "l"
-        ];;
-        Continue
-      else
-        let: "p" := FS.readAt "f" (struct.get lazyFileBuf.S "offset" (![struct.t lazyFileBuf.S] "buf") + slice.len (struct.get lazyFileBuf.S "next" (![struct.t lazyFileBuf.S] "buf"))) #4096 in
-        (if: (slice.len "p" = #0)
-        then Break
-        else
-          let: "newBuf" := SliceAppendSlice byteT (struct.get lazyFileBuf.S "next" (![struct.t lazyFileBuf.S] "buf")) "p" in
-          "buf" <-[struct.t lazyFileBuf.S] struct.mk lazyFileBuf.S [
-            "offset" ::= struct.get lazyFileBuf.S "offset" (![struct.t lazyFileBuf.S] "buf");
-            "next" ::= "newBuf"
-          ];;
-          Continue));;
-      Continue).
+    (let: "buf" := ref_to (struct.t lazyFileBuf.S) (struct.mk lazyFileBuf.S [
+       "offset" ::= #0;
+       "next" ::= slice.nil
+     ]) in
+     (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
+       let: ("e", "l") := DecodeEntry (struct.get lazyFileBuf.S "next" (![struct.t lazyFileBuf.S] "buf")) in
+       (if: "l" > #0
+       then
+         MapInsert "index" (struct.get Entry.S "Key" "e") (#8 + struct.get lazyFileBuf.S "offset" (![struct.t lazyFileBuf.S] "buf"));;
+         "buf" <-[struct.t lazyFileBuf.S] struct.mk lazyFileBuf.S [
+           "offset" ::= struct.get lazyFileBuf.S "offset" (![struct.t lazyFileBuf.S] "buf") + "l";
+           "next" ::= SliceSkip byteT (struct.get lazyFileBuf.S "next" (![struct.t lazyFileBuf.S] "buf")) "l"
+         ];;
+         Continue
+       else
+         let: "p" := FS.readAt "f" (struct.get lazyFileBuf.S "offset" (![struct.t lazyFileBuf.S] "buf") + slice.len (struct.get lazyFileBuf.S "next" (![struct.t lazyFileBuf.S] "buf"))) #4096 in
+         (if: (slice.len "p" = #0)
+         then Break
+         else
+           let: "newBuf" := SliceAppendSlice byteT (struct.get lazyFileBuf.S "next" (![struct.t lazyFileBuf.S] "buf")) "p" in
+           "buf" <-[struct.t lazyFileBuf.S] struct.mk lazyFileBuf.S [
+             "offset" ::= struct.get lazyFileBuf.S "offset" (![struct.t lazyFileBuf.S] "buf");
+             "next" ::= "newBuf"
+           ];;
+           Continue));;
+       Continue)).
 
 (* RecoverTable restores a table from disk on startup. *)
 Definition RecoverTable: val :=
@@ -360,37 +360,37 @@ Definition tablePutBuffer: val :=
    buffer b since those writes overwrite old ones *)
 Definition tablePutOldTable: val :=
   rec: "tablePutOldTable" "w" "t" "b" :=
-    let: "buf" := ref_to (struct.t lazyFileBuf.S) (struct.mk lazyFileBuf.S [
-      "offset" ::= #0;
-      "next" ::= slice.nil
-    ]) in
-    (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
-      let: ("e", "l") := DecodeEntry (struct.get lazyFileBuf.S "next" (![struct.t lazyFileBuf.S] "buf")) in
-      (if: "l" > #0
-      then
-        let: (<>, "ok") := MapGet "b" (struct.get Entry.S "Key" "e") in
-        (if: ~ "ok"
-        then
-          tablePut "w" (struct.get Entry.S "Key" "e") (struct.get Entry.S "Value" "e");;
-          #()
-        else #());;
-        "buf" <-[struct.t lazyFileBuf.S] struct.mk lazyFileBuf.S [
-          "offset" ::= struct.get lazyFileBuf.S "offset" (![struct.t lazyFileBuf.S] "buf") + "l";
-          "next" ::= SliceSkip byteT (struct.get lazyFileBuf.S "next" (![struct.t lazyFileBuf.S] "buf")) "l"
-        ];;
-        Continue
-      else
-        let: "p" := FS.readAt (struct.get Table.S "File" "t") (struct.get lazyFileBuf.S "offset" (![struct.t lazyFileBuf.S] "buf") + slice.len (struct.get lazyFileBuf.S "next" (![struct.t lazyFileBuf.S] "buf"))) #4096 in
-        (if: (slice.len "p" = #0)
-        then Break
-        else
-          let: "newBuf" := SliceAppendSlice byteT (struct.get lazyFileBuf.S "next" (![struct.t lazyFileBuf.S] "buf")) "p" in
-          "buf" <-[struct.t lazyFileBuf.S] struct.mk lazyFileBuf.S [
-            "offset" ::= struct.get lazyFileBuf.S "offset" (![struct.t lazyFileBuf.S] "buf");
-            "next" ::= "newBuf"
-          ];;
-          Continue));;
-      Continue).
+    (let: "buf" := ref_to (struct.t lazyFileBuf.S) (struct.mk lazyFileBuf.S [
+       "offset" ::= #0;
+       "next" ::= slice.nil
+     ]) in
+     (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
+       let: ("e", "l") := DecodeEntry (struct.get lazyFileBuf.S "next" (![struct.t lazyFileBuf.S] "buf")) in
+       (if: "l" > #0
+       then
+         let: (<>, "ok") := MapGet "b" (struct.get Entry.S "Key" "e") in
+         (if: ~ "ok"
+         then
+           tablePut "w" (struct.get Entry.S "Key" "e") (struct.get Entry.S "Value" "e");;
+           #()
+         else #());;
+         "buf" <-[struct.t lazyFileBuf.S] struct.mk lazyFileBuf.S [
+           "offset" ::= struct.get lazyFileBuf.S "offset" (![struct.t lazyFileBuf.S] "buf") + "l";
+           "next" ::= SliceSkip byteT (struct.get lazyFileBuf.S "next" (![struct.t lazyFileBuf.S] "buf")) "l"
+         ];;
+         Continue
+       else
+         let: "p" := FS.readAt (struct.get Table.S "File" "t") (struct.get lazyFileBuf.S "offset" (![struct.t lazyFileBuf.S] "buf") + slice.len (struct.get lazyFileBuf.S "next" (![struct.t lazyFileBuf.S] "buf"))) #4096 in
+         (if: (slice.len "p" = #0)
+         then Break
+         else
+           let: "newBuf" := SliceAppendSlice byteT (struct.get lazyFileBuf.S "next" (![struct.t lazyFileBuf.S] "buf")) "p" in
+           "buf" <-[struct.t lazyFileBuf.S] struct.mk lazyFileBuf.S [
+             "offset" ::= struct.get lazyFileBuf.S "offset" (![struct.t lazyFileBuf.S] "buf");
+             "next" ::= "newBuf"
+           ];;
+           Continue));;
+       Continue)).
 
 (* Build a new shadow table that incorporates the current table and a
    (write) buffer wbuf.
@@ -457,15 +457,15 @@ Definition deleteOtherFiles: val :=
   rec: "deleteOtherFiles" "tableName" :=
     let: "files" := FS.list #(str"db") in
     let: "nfiles" := slice.len "files" in
-    let: "i" := ref_to uint64T #0 in
-    (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
-      (if: (![uint64T] "i" = "nfiles")
-      then Break
-      else
-        let: "name" := SliceGet stringT "files" (![uint64T] "i") in
-        deleteOtherFile "name" "tableName";;
-        "i" <-[uint64T] ![uint64T] "i" + #1;;
-        Continue)).
+    (let: "i" := ref_to uint64T #0 in
+     (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
+       (if: (![uint64T] "i" = "nfiles")
+       then Break
+       else
+         let: "name" := SliceGet stringT "files" (![uint64T] "i") in
+         deleteOtherFile "name" "tableName";;
+         "i" <-[uint64T] ![uint64T] "i" + #1;;
+         Continue))).
 
 (* Recover restores a previously created database after a crash or shutdown. *)
 Definition Recover: val :=
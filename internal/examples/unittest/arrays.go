@@ -0,0 +1,43 @@
+package unittest
+
+type header struct {
+	magic [4]byte
+	size  uint64
+}
+
+func arrayZero() uint64 {
+	var a [5]uint64
+	return a[2] + uint64(len(a))
+}
+
+func arrayAssign() [3]uint64 {
+	var a [3]uint64
+	a[0] = 1
+	a[1] = a[0] + 1
+	b := a
+	return b
+}
+
+func arrayPassedByValue(a [3]uint64) uint64 {
+	var b = a
+	b[0] = 2
+	return a[0]
+}
+
+func arraySlice() []byte {
+	var buf [16]byte
+	buf[1] = 7
+	return buf[:4]
+}
+
+func arrayInStruct(h *header) []byte {
+	h.magic[0] = 0x7f
+	x := &h.magic[1]
+	*x = 'E'
+	return h.magic[:]
+}
+
+func arrayPointer(p *[8]uint32) uint32 {
+	p[2] = 3
+	return p[2] + uint32(len(p))
+}
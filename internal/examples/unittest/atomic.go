@@ -0,0 +1,41 @@
+package unittest
+
+import "sync/atomic"
+
+type atomicCounter struct {
+	hits   atomic.Uint64
+	misses atomic.Uint32
+}
+
+func (c *atomicCounter) hit() uint64 {
+	return c.hits.Add(1)
+}
+
+func (c *atomicCounter) miss() {
+	c.misses.Add(1)
+}
+
+func (c *atomicCounter) reset() {
+	c.hits.Store(0)
+	c.misses.Store(0)
+}
+
+func newAtomicCounter() *atomicCounter {
+	return &atomicCounter{}
+}
+
+func atomicOps() bool {
+	var x uint64
+	atomic.StoreUint64(&x, 3)
+	atomic.AddUint64(&x, 2)
+	var y uint32
+	atomic.AddUint32(&y, 1)
+	if atomic.LoadUint32(&y) != 1 {
+		return false
+	}
+	return atomic.CompareAndSwapUint64(&x, 5, atomic.LoadUint64(&x)+1)
+}
+
+func incrAtomic(p *atomic.Uint64) uint64 {
+	return p.Add(1)
+}
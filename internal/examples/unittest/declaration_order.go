@@ -0,0 +1,19 @@
+package unittest
+
+// Declarations can refer to functions, types and constants declared later
+// (even in another file); goose outputs them in dependency order.
+
+func useLaterStruct() uint64 {
+	s := laterStruct{x: laterConstant}
+	return s.get() + stringLength(GlobalConstant)
+}
+
+const laterConstant uint64 = 3
+
+type laterStruct struct {
+	x uint64
+}
+
+func (s laterStruct) get() uint64 {
+	return s.x
+}
@@ -0,0 +1,43 @@
+package unittest
+
+// checksum is trusted rather than translated, since it uses signed integers.
+//
+//goose:axiom
+func checksum(data []byte) uint64 {
+	var sum int
+	for _, b := range data {
+		sum += int(b)
+	}
+	return uint64(sum)
+}
+
+//goose:skip
+func debugDump(data []byte) int {
+	return len(data)
+}
+
+// blockSize is translated but opaque to proofs.
+//
+//goose:opaque
+func blockSize() uint64 {
+	return 4096
+}
+
+func checksumBlock(b []byte) uint64 {
+	return checksum(b[:blockSize()])
+}
+
+type counter struct {
+	n uint64
+}
+
+//goose:axiom
+func (c *counter) addWrapping(delta uint64) {
+	c.n = uint64(int(c.n) + int(delta))
+}
+
+//goose:skip
+var (
+	debugLevel   = 2
+	debugVerbose = false
+)
@@ -0,0 +1,45 @@
+package unittest
+
+type pairWithName struct {
+	name string
+	x    uint64
+	y    uint64
+}
+
+type nestedPair struct {
+	p     pairWithName
+	flags [2]bool
+}
+
+func comparePairs(a pairWithName, b pairWithName) bool {
+	return a == b
+}
+
+func differentPairs(a *pairWithName, b pairWithName) bool {
+	return *a != b
+}
+
+func compareNested(a nestedPair, b nestedPair) bool {
+	return a == b
+}
+
+func compareArrays(a [3]uint64) bool {
+	var b [3]uint64
+	return a == b
+}
+
+type digest struct {
+	hash [32]byte
+}
+
+func compareHashes(a [32]byte, b [32]byte) bool {
+	return a == b
+}
+
+func compareDigests(a digest, b digest) bool {
+	return a == b
+}
+
+func compareHashTable(a [20][32]byte, b [20][32]byte) bool {
+	return a == b
+}
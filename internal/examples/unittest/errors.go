@@ -0,0 +1,53 @@
+package unittest
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound is returned by lookup for a missing key.
+var ErrNotFound = errors.New("not found")
+
+type errorTable struct {
+	vals map[uint64]uint64
+	err  error
+}
+
+func (t errorTable) lookup(k uint64) (uint64, error) {
+	v, ok := t.vals[k]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return v, nil
+}
+
+func newErrorTable() errorTable {
+	return errorTable{vals: make(map[uint64]uint64), err: nil}
+}
+
+func checkPositive(x uint64) error {
+	if x == 0 {
+		return fmt.Errorf("expected positive number, got %d", x)
+	}
+	return nil
+}
+
+func useErrors(t errorTable) bool {
+	v, err := t.lookup(3)
+	if err == ErrNotFound {
+		return false
+	}
+	if err != nil {
+		return false
+	}
+	return checkPositive(v) == nil
+}
+
+func handleError(err error) {}
+
+func resetError() {
+	var err error = errors.New("unknown")
+	err = nil
+	handleError(err)
+	handleError(nil)
+}
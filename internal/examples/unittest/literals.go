@@ -29,3 +29,29 @@ func oddLiterals() allTheLiterals {
 		b:   false,
 	}
 }
+
+func quotedString() string {
+	return "say \"hello\""
+}
+
+func backslashString() string {
+	return `C:\path\to\file`
+}
+
+func stringWithEscapes() string {
+	return "line\nbreak\ttab\x00null"
+}
+
+func unicodeString() string {
+	return "héllo"
+}
+
+func runeLiterals(b byte) bool {
+	return b == 'a' || b == '\n'
+}
+
+const magicNumber uint64 = 0xDEAD_BEEF
+
+func otherIntLiterals() uint64 {
+	return 0o17 + 0b1010 + 1_000_000 + magicNumber
+}
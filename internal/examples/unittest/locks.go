@@ -19,6 +19,32 @@ func useCondVar() {
 }
 
 type hasCondVar struct {
-	// sync.Cond on its own is not supported
 	cond *sync.Cond
 }
+
+type hasLocksByValue struct {
+	mu   sync.Mutex
+	cond sync.Cond
+	x    uint64
+}
+
+func newHasLocksByValue() *hasLocksByValue {
+	h := &hasLocksByValue{x: 0}
+	h.cond.L = &h.mu
+	return h
+}
+
+func (h *hasLocksByValue) incr() {
+	h.mu.Lock()
+	h.x = h.x + 1
+	h.cond.Broadcast()
+	h.mu.Unlock()
+}
+
+func useLocalLock() {
+	var mu sync.Mutex
+	mu.Lock()
+	mu.Unlock()
+	c := sync.NewCond(&mu)
+	c.Signal()
+}
@@ -87,3 +87,73 @@ func breakFromLoop() {
 		continue
 	}
 }
+
+type blockList []uint64
+
+type blockIndex map[uint64]uint64
+
+func sumBlockList(bs blockList, idx blockIndex) uint64 {
+	var sum uint64
+	for _, b := range bs {
+		sum = sum + b
+	}
+	for k, v := range idx {
+		sum = sum + k + v
+	}
+	return sum
+}
+
+func useInt(x uint64) {}
+
+func rangeOverInt(n uint64) {
+	for i := range n {
+		DoSomething("counting")
+		useInt(i)
+	}
+	for range uint64(3) {
+		DoSomething("three times")
+	}
+}
+
+func rangeAssign(s []uint64, m map[uint64]uint64) uint64 {
+	var last uint64
+	var lastVal uint64
+	for _, lastVal = range s {
+	}
+	for last = range m {
+	}
+	return last + lastVal
+}
+
+func reverseBytes(b []byte) {
+	for i, j := uint64(0), uint64(len(b))-1; i < j; i, j = i+1, j-1 {
+		x := b[i]
+		b[i] = b[j]
+		b[j] = x
+	}
+}
+
+func loopWithoutInit(n uint64) uint64 {
+	var i uint64
+	var steps uint64
+	for ; i < n; i = i + 2 {
+		steps = steps + 1
+	}
+	return steps
+}
+
+func swapInLoopInit(i uint64, j uint64) uint64 {
+	var sum uint64
+	for i, j := j, i; i < j; i++ {
+		sum = sum + i
+	}
+	return sum
+}
+
+func loopVarScope(i uint64) uint64 {
+	var sum uint64
+	for i := uint64(0); i < 3; i++ {
+		sum = sum + i
+	}
+	return sum + i
+}
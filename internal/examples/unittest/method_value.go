@@ -0,0 +1,41 @@
+package unittest
+
+type Log struct {
+	entries []uint64
+}
+
+func (l *Log) Append(x uint64) {
+	l.entries = append(l.entries, x)
+}
+
+func (l *Log) Flush() {
+	l.entries = nil
+}
+
+func applyAll(f func(uint64), xs []uint64) {
+	for _, x := range xs {
+		f(x)
+	}
+}
+
+func runThunk(f func()) {
+	f()
+}
+
+func useMethodValues(l *Log) {
+	applyAll(l.Append, []uint64{3})
+	runThunk(l.Flush)
+}
+
+func useMethodExpr(l *Log) {
+	appendTo := (*Log).Append
+	appendTo(l, 4)
+	(*Log).Append(l, 5)
+}
+
+func useMethodValueOfVar() {
+	var l = &Log{entries: nil}
+	f := l.Flush
+	l = &Log{entries: nil}
+	runThunk(f)
+}
@@ -0,0 +1,32 @@
+package unittest
+
+func isEven(x uint64) bool {
+	if x == 0 {
+		return true
+	}
+	return isOdd(x - 1)
+}
+
+// isOdd is defined along with isEven
+func isOdd(x uint64) bool {
+	if x == 0 {
+		return false
+	}
+	return isEven(x - 1)
+}
+
+type binTree struct {
+	left  *binTree
+	right *binTree
+}
+
+func (t *binTree) size() uint64 {
+	if t == nil {
+		return 0
+	}
+	return 1 + childrenSize(t)
+}
+
+func childrenSize(t *binTree) uint64 {
+	return t.left.size() + t.right.size()
+}
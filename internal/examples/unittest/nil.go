@@ -19,3 +19,34 @@ func ComparePointerToNil() bool {
 	s := new(uint64)
 	return s != nil
 }
+
+func CompareMapToNil(m map[uint64]uint64) bool {
+	return m == nil
+}
+
+func CompareFuncToNil(f func()) bool {
+	return nil != f
+}
+
+func useNils(m map[uint64]bool, f func(uint64), x interface{}, ps []*uint64, s [][]byte) {}
+
+func NilValues() {
+	var m map[uint64]bool = nil
+	var f func(uint64) = nil
+	var x interface{} = nil
+	var ps = make([]*uint64, 0)
+	ps = append(ps, nil)
+	s := [][]byte(nil)
+	useNils(m, f, x, ps, s)
+}
+
+type nilHolder struct {
+	p *uint64
+	m map[uint64]bool
+}
+
+func NilElements() ([]*uint64, nilHolder) {
+	xs := []*uint64{nil}
+	h := nilHolder{p: nil, m: nil}
+	return xs, h
+}
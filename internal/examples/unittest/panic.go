@@ -1,5 +1,54 @@
 package unittest
 
+import "sync"
+
 func PanicAtTheDisco() {
 	panic("disco")
 }
+
+func panicWithError(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+const panicMessage = "constant message"
+
+func panicWithConstant() {
+	panic(panicMessage)
+}
+
+func handleRecovered(r interface{}) {}
+
+func recoverPanic() bool {
+	defer func() {
+		r := recover()
+		if r != nil {
+			handleRecovered(r)
+		}
+	}()
+	panicWithError(ErrNotFound)
+	return true
+}
+
+type deferredCounter struct {
+	mu    *sync.Mutex
+	count uint64
+}
+
+func (c *deferredCounter) incr() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count = c.count + 1
+	return c.count
+}
+
+func useDeferred(x uint64) {}
+
+func deferShadowing() {
+	x := uint64(1)
+	defer func(x uint64) {
+		useDeferred(x)
+	}(2)
+	useDeferred(x)
+}
@@ -0,0 +1,11 @@
+package unittest
+
+import (
+	_ "log"
+
+	d "github.com/tchajed/goose/machine/disk"
+)
+
+func renamedDiskRead(a uint64) d.Block {
+	return d.Read(a)
+}
@@ -38,3 +38,33 @@ func loopSpawn() {
 		continue
 	}
 }
+
+type worker struct {
+	id uint64
+}
+
+func (w *worker) background() {}
+
+func (w *worker) process(job uint64, data []byte) {}
+
+func spawnWithArgs(w *worker) {
+	go threadCode(3)
+	for i := uint64(0); i < 2; i++ {
+		go threadCode(i)
+	}
+	go w.background()
+	go w.process(w.id, nil)
+	go func(x uint64) {
+		threadCode(x)
+	}(w.id)
+	f := threadCode
+	go f(1)
+}
+
+func spawnShadowing() {
+	x := uint64(1)
+	go func(x uint64) {
+		threadCode(x)
+	}(2)
+	threadCode(x)
+}
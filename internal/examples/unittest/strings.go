@@ -9,3 +9,50 @@ func stringAppend(s string, x uint64) string {
 func stringLength(s string) uint64 {
 	return uint64(len(s))
 }
+
+func stringIndex(s string, i uint64) byte {
+	return s[i]
+}
+
+func stringSlices(s string) string {
+	return s[1:] + s[:2] + s[1:3]
+}
+
+func stringCompare(s1 string, s2 string) bool {
+	return s1 < s2 || s1 >= s2 && s1 != "foo"
+}
+
+func stringCountSlashes(s string) uint64 {
+	var n = uint64(0)
+	for _, b := range []byte(s) {
+		if b == '/' {
+			n++
+		}
+	}
+	return n
+}
+
+func stringLastRune(s string) uint32 {
+	var last = uint32(0)
+	for i, r := range s {
+		if i > 0 && r != 'é' {
+			last = uint32(r)
+		}
+	}
+	return last
+}
+
+func isDigitRune(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func countDigits(s string) uint64 {
+	var n = uint64(0)
+	for _, r := range s {
+		var c rune = r
+		if isDigitRune(c) {
+			n = n + 1
+		}
+	}
+	return n
+}
@@ -16,3 +16,48 @@ func makeLock() {
 	l := new(sync.Mutex)
 	DoSomeLocking(l)
 }
+
+type cache struct {
+	mu      *sync.RWMutex
+	entries map[uint64]uint64
+}
+
+func (c *cache) get(k uint64) uint64 {
+	c.mu.RLock()
+	v := c.entries[k]
+	c.mu.RUnlock()
+	return v
+}
+
+func (c *cache) put(k uint64, v uint64) {
+	c.mu.Lock()
+	c.entries[k] = v
+	c.mu.Unlock()
+}
+
+func waitForWorkers(n uint64) {
+	wg := new(sync.WaitGroup)
+	for i := uint64(0); i < n; i++ {
+		wg.Add(1)
+		go func() {
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+}
+
+type lazyInit struct {
+	once *sync.Once
+	val  *uint64
+}
+
+func (l *lazyInit) get() uint64 {
+	l.once.Do(func() {
+		*l.val = 1
+	})
+	return *l.val
+}
+
+func newLazyInit() *lazyInit {
+	return &lazyInit{once: new(sync.Once), val: new(uint64)}
+}
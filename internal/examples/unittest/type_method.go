@@ -0,0 +1,42 @@
+package unittest
+
+type Inum uint64
+
+func (i Inum) IsRoot() bool {
+	return i == 1
+}
+
+type Bitmap []byte
+
+func (bm Bitmap) Get(i uint64) bool {
+	return bm[i/8]&(1<<(i%8)) != 0
+}
+
+func (bm Bitmap) Set(i uint64) {
+	bm[i/8] = bm[i/8] | (1 << (i % 8))
+}
+
+func useTypeMethods(bm Bitmap, i Inum) bool {
+	if i.IsRoot() {
+		return true
+	}
+	bm.Set(uint64(i))
+	return bm.Get(uint64(i))
+}
+
+type Counter uint64
+
+func (c *Counter) Inc() {
+	*c = *c + 1
+}
+
+func (c Counter) Get() uint64 {
+	return uint64(c)
+}
+
+func useCounter(p *Counter) uint64 {
+	var c Counter = 0
+	c.Inc()
+	p.Inc()
+	return c.Get() + p.Get()
+}
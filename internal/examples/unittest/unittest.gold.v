@@ -4,6 +4,103 @@ From Perennial.goose_lang Require Import ffi.disk_prelude.
 
 From Goose Require github_com.tchajed.marshal.
 
+(* trusted declarations:
+   goose:axiom checksum (directives.go:6)
+   goose:skip debugDump (directives.go:15)
+   goose:opaque blockSize (directives.go:22)
+   goose:axiom counter.addWrapping (directives.go:35)
+   goose:skip debugLevel, debugVerbose (directives.go:40) *)
+
+(* arrays.go *)
+
+Module header.
+  Definition S := struct.decl [
+    "magic" :: arrayT 4 byteT;
+    "size" :: uint64T
+  ].
+End header.
+
+Definition arrayZero: val :=
+  rec: "arrayZero" <> :=
+    let: "a" := ref (zero_val (arrayT 5 uint64T)) in
+    ArrayGet uint64T (![arrayT 5 uint64T] "a") #2 + #5.
+
+Definition arrayAssign: val :=
+  rec: "arrayAssign" <> :=
+    let: "a" := ref (zero_val (arrayT 3 uint64T)) in
+    ArrayRef uint64T "a" #0 <-[uint64T] #1;;
+    ArrayRef uint64T "a" #1 <-[uint64T] ArrayGet uint64T (![arrayT 3 uint64T] "a") #0 + #1;;
+    let: "b" := ![arrayT 3 uint64T] "a" in
+    "b".
+
+Definition arrayPassedByValue: val :=
+  rec: "arrayPassedByValue" "a" :=
+    let: "b" := ref_to (arrayT 3 uint64T) "a" in
+    ArrayRef uint64T "b" #0 <-[uint64T] #2;;
+    ArrayGet uint64T "a" #0.
+
+Definition arraySlice: val :=
+  rec: "arraySlice" <> :=
+    let: "buf" := ref (zero_val (arrayT 16 byteT)) in
+    ArrayRef byteT "buf" #1 <-[byteT] #(U8 7);;
+    SliceTake (ArrayToSlice byteT "buf" #16) #4.
+
+Definition arrayInStruct: val :=
+  rec: "arrayInStruct" "h" :=
+    ArrayRef byteT (struct.fieldRef header.S "magic" "h") #0 <-[byteT] #(U8 127);;
+    let: "x" := ArrayRef byteT (struct.fieldRef header.S "magic" "h") #1 in
+    "x" <-[byteT] #(U8 69);;
+    ArrayToSlice byteT (struct.fieldRef header.S "magic" "h") #4.
+
+Definition arrayPointer: val :=
+  rec: "arrayPointer" "p" :=
+    ArrayRef uint32T "p" #2 <-[uint32T] #(U32 3);;
+    ![uint32T] (ArrayRef uint32T "p" #2) + to_u32 #8.
+
+(* atomic.go *)
+
+Module atomicCounter.
+  Definition S := struct.decl [
+    "hits" :: uint64T;
+    "misses" :: uint32T
+  ].
+End atomicCounter.
+
+Definition atomicCounter__hit: val :=
+  rec: "atomicCounter__hit" "c" :=
+    atomic.AddUint64 (struct.fieldRef atomicCounter.S "hits" "c") #1.
+
+Definition atomicCounter__miss: val :=
+  rec: "atomicCounter__miss" "c" :=
+    atomic.AddUint32 (struct.fieldRef atomicCounter.S "misses" "c") (#(U32 1)).
+
+Definition atomicCounter__reset: val :=
+  rec: "atomicCounter__reset" "c" :=
+    atomic.StoreUint64 (struct.fieldRef atomicCounter.S "hits" "c") #0;;
+    atomic.StoreUint32 (struct.fieldRef atomicCounter.S "misses" "c") (#(U32 0)).
+
+Definition newAtomicCounter: val :=
+  rec: "newAtomicCounter" <> :=
+    struct.new atomicCounter.S [
+      "hits" ::= zero_val uint64T;
+      "misses" ::= zero_val uint32T
+    ].
+
+Definition atomicOps: val :=
+  rec: "atomicOps" <> :=
+    let: "x" := ref (zero_val uint64T) in
+    atomic.StoreUint64 "x" #3;;
+    atomic.AddUint64 "x" #2;;
+    let: "y" := ref (zero_val uint32T) in
+    atomic.AddUint32 "y" (#(U32 1));;
+    (if: atomic.LoadUint32 "y" ≠ #(U32 1)
+    then #false
+    else atomic.CompareAndSwapUint64 "x" #5 (atomic.LoadUint64 "x" + #1)).
+
+Definition incrAtomic: val :=
+  rec: "incrAtomic" "p" :=
+    atomic.AddUint64 "p" #1.
+
 (* comments.go *)
 
 (* This struct is very important.
@@ -206,6 +303,58 @@ Definition getRandom: val :=
     let: "r" := Data.randomUint64 #() in
     "r".
 
+(* declaration_order.go *)
+
+Definition laterConstant : expr := #3.
+
+Module laterStruct.
+  Definition S := struct.decl [
+    "x" :: uint64T
+  ].
+End laterStruct.
+
+Definition laterStruct__get: val :=
+  rec: "laterStruct__get" "s" :=
+    struct.get laterStruct.S "x" "s".
+
+(* strings.go *)
+
+Definition stringLength: val :=
+  rec: "stringLength" "s" :=
+    strLen "s".
+
+(* declaration_order.go *)
+
+Definition useLaterStruct: val :=
+  rec: "useLaterStruct" <> :=
+    let: "s" := struct.mk laterStruct.S [
+      "x" ::= laterConstant
+    ] in
+    laterStruct__get "s" + stringLength GlobalConstant.
+
+(* directives.go *)
+
+(* checksum is trusted rather than translated, since it uses signed integers. *)
+Axiom checksum: val.
+
+(* blockSize is translated but opaque to proofs. *)
+Definition blockSize: val :=
+  rec: "blockSize" <> :=
+    #4096.
+Global Opaque blockSize.
+
+Definition checksumBlock: val :=
+  rec: "checksumBlock" "b" :=
+    checksum (SliceTake "b" (blockSize #())).
+
+Module counter.
+  Definition S := struct.decl [
+    "n" :: uint64T
+  ].
+End counter.
+
+Axiom counter__addWrapping: val.
+
 (* disk.go *)
 
 Module diskWrapper.
@@ -271,6 +420,133 @@ Definition Dec__UInt32: val :=
   rec: "Dec__UInt32" "d" :=
     UInt32Get (Dec__consume "d" #4).
 
+(* equality.go *)
+
+Module pairWithName.
+  Definition S := struct.decl [
+    "name" :: stringT;
+    "x" :: uint64T;
+    "y" :: uint64T
+  ].
+End pairWithName.
+
+Module nestedPair.
+  Definition S := struct.decl [
+    "p" :: struct.t pairWithName.S;
+    "flags" :: arrayT 2 boolT
+  ].
+End nestedPair.
+
+Definition comparePairs: val :=
+  rec: "comparePairs" "a" "b" :=
+    (struct.get pairWithName.S "name" "a" = struct.get pairWithName.S "name" "b") && (struct.get pairWithName.S "x" "a" = struct.get pairWithName.S "x" "b") && (struct.get pairWithName.S "y" "a" = struct.get pairWithName.S "y" "b").
+
+Definition differentPairs: val :=
+  rec: "differentPairs" "a" "b" :=
+    ~ (let: "$x" := struct.load pairWithName.S "a" in
+     (struct.get pairWithName.S "name" "$x" = struct.get pairWithName.S "name" "b") && (struct.get pairWithName.S "x" "$x" = struct.get pairWithName.S "x" "b") && (struct.get pairWithName.S "y" "$x" = struct.get pairWithName.S "y" "b")).
+
+Definition compareNested: val :=
+  rec: "compareNested" "a" "b" :=
+    (struct.get pairWithName.S "name" (struct.get nestedPair.S "p" "a") = struct.get pairWithName.S "name" (struct.get nestedPair.S "p" "b")) && (struct.get pairWithName.S "x" (struct.get nestedPair.S "p" "a") = struct.get pairWithName.S "x" (struct.get nestedPair.S "p" "b")) && (struct.get pairWithName.S "y" (struct.get nestedPair.S "p" "a") = struct.get pairWithName.S "y" (struct.get nestedPair.S "p" "b")) && (ArrayGet boolT (struct.get nestedPair.S "flags" "a") #0 = ArrayGet boolT (struct.get nestedPair.S "flags" "b") #0) && (ArrayGet boolT (struct.get nestedPair.S "flags" "a") #1 = ArrayGet boolT (struct.get nestedPair.S "flags" "b") #1).
+
+Definition compareArrays: val :=
+  rec: "compareArrays" "a" :=
+    let: "b" := ref (zero_val (arrayT 3 uint64T)) in
+    (let: "$y" := ![arrayT 3 uint64T] "b" in
+     (ArrayGet uint64T "a" #0 = ArrayGet uint64T "$y" #0) && (ArrayGet uint64T "a" #1 = ArrayGet uint64T "$y" #1) && (ArrayGet uint64T "a" #2 = ArrayGet uint64T "$y" #2)).
+
+Module digest.
+  Definition S := struct.decl [
+    "hash" :: arrayT 32 byteT
+  ].
+End digest.
+
+Definition compareHashes: val :=
+  rec: "compareHashes" "a" "b" :=
+    (let: "$eq0" := ref_to boolT #true in
+     ForRange uint64T "$i0" #32
+       (if: ~ (ArrayGet byteT "a" "$i0" = ArrayGet byteT "b" "$i0")
+       then "$eq0" <-[boolT] #false
+       else #());;
+     ![boolT] "$eq0").
+
+Definition compareDigests: val :=
+  rec: "compareDigests" "a" "b" :=
+    (let: "$eq0" := ref_to boolT #true in
+     ForRange uint64T "$i0" #32
+       (if: ~ (ArrayGet byteT (struct.get digest.S "hash" "a") "$i0" = ArrayGet byteT (struct.get digest.S "hash" "b") "$i0")
+       then "$eq0" <-[boolT] #false
+       else #());;
+     ![boolT] "$eq0").
+
+Definition compareHashTable: val :=
+  rec: "compareHashTable" "a" "b" :=
+    (let: "$eq0" := ref_to boolT #true in
+     ForRange uint64T "$i0" #20
+       (if: ~ (let: "$eq1" := ref_to boolT #true in
+        ForRange uint64T "$i1" #32
+          (if: ~ (ArrayGet byteT (ArrayGet (arrayT 32 byteT) "a" "$i0") "$i1" = ArrayGet byteT (ArrayGet (arrayT 32 byteT) "b" "$i0") "$i1")
+          then "$eq1" <-[boolT] #false
+          else #());;
+        ![boolT] "$eq1")
+       then "$eq0" <-[boolT] #false
+       else #());;
+     ![boolT] "$eq0").
+
+(* errors.go *)
+
+(* ErrNotFound is returned by lookup for a missing key. *)
+Definition ErrNotFound: val := #(str"unittest.ErrNotFound").
+
+Module errorTable.
+  Definition S := struct.decl [
+    "vals" :: mapT uint64T;
+    "err" :: errorT
+  ].
+End errorTable.
+
+Definition errorTable__lookup: val :=
+  rec: "errorTable__lookup" "t" "k" :=
+    let: ("v", "ok") := MapGet (struct.get errorTable.S "vals" "t") "k" in
+    (if: ~ "ok"
+    then (#0, ErrNotFound)
+    else ("v", #null)).
+
+Definition newErrorTable: val :=
+  rec: "newErrorTable" <> :=
+    struct.mk errorTable.S [
+      "vals" ::= NewMap uint64T;
+      "err" ::= #null
+    ].
+
+Definition checkPositive: val :=
+  rec: "checkPositive" "x" :=
+    (if: ("x" = #0)
+    then errors.Errorf (#(str"expected positive number, got %d")) (slice.literal anyT ["x"])
+    else #null).
+
+Definition useErrors: val :=
+  rec: "useErrors" "t" :=
+    let: ("v", "err") := errorTable__lookup "t" #3 in
+    (if: ("err" = ErrNotFound)
+    then #false
+    else
+      (if: "err" ≠ #null
+      then #false
+      else (checkPositive "v" = #null))).
+
+Definition handleError: val :=
+  rec: "handleError" "err" :=
+    #().
+
+Definition resetError: val :=
+  rec: "resetError" <> :=
+    let: "err" := ref_to errorT (errors.New #(str"unknown")) in
+    "err" <-[errorT] #null;;
+    handleError (![errorT] "err");;
+    handleError #null.
+
 (* ints.go *)
 
 Definition useInts: val :=
@@ -322,6 +598,32 @@ Definition oddLiterals: val :=
       "b" ::= #false
     ].
 
+Definition quotedString: val :=
+  rec: "quotedString" <> :=
+    #(str"say ""hello""").
+
+Definition backslashString: val :=
+  rec: "backslashString" <> :=
+    #(str"C:\path\to\file").
+
+Definition stringWithEscapes: val :=
+  rec: "stringWithEscapes" <> :=
+    #(str ("line" ++ String "010"%char ("break" ++ String "009"%char ("tab" ++ String "000"%char "null")))%string).
+
+Definition unicodeString: val :=
+  rec: "unicodeString" <> :=
+    #(str"héllo").
+
+Definition runeLiterals: val :=
+  rec: "runeLiterals" "b" :=
+    ("b" = #(U8 97)) || ("b" = #(U8 10)).
+
+Definition magicNumber : expr := #3735928559.
+
+Definition otherIntLiterals: val :=
+  rec: "otherIntLiterals" <> :=
+    #15 + #10 + #1000000 + magicNumber.
+
 (* locks.go *)
 
 Definition useLocks: val :=
@@ -345,6 +647,39 @@ Module hasCondVar.
   ].
 End hasCondVar.
 
+Module hasLocksByValue.
+  Definition S := struct.decl [
+    "mu" :: lockT;
+    "cond" :: condvarT;
+    "x" :: uint64T
+  ].
+End hasLocksByValue.
+
+Definition newHasLocksByValue: val :=
+  rec: "newHasLocksByValue" <> :=
+    let: "h" := struct.new hasLocksByValue.S [
+      "x" ::= #0;
+      "mu" ::= zero_val lockT;
+      "cond" ::= zero_val condvarT
+    ] in
+    lock.condSetLock (struct.fieldRef hasLocksByValue.S "cond" "h") (struct.fieldRef hasLocksByValue.S "mu" "h");;
+    "h".
+
+Definition hasLocksByValue__incr: val :=
+  rec: "hasLocksByValue__incr" "h" :=
+    lock.acquire (struct.fieldRef hasLocksByValue.S "mu" "h");;
+    struct.storeF hasLocksByValue.S "x" "h" (struct.loadF hasLocksByValue.S "x" "h" + #1);;
+    lock.condBroadcast (struct.fieldRef hasLocksByValue.S "cond" "h");;
+    lock.release (struct.fieldRef hasLocksByValue.S "mu" "h").
+
+Definition useLocalLock: val :=
+  rec: "useLocalLock" <> :=
+    let: "mu" := ref (zero_val lockT) in
+    lock.acquire "mu";;
+    lock.release "mu";;
+    let: "c" := lock.newCond "mu" in
+    lock.condSignal "c".
+
 (* log_debugging.go *)
 
 Definition ToBeDebugged: val :=
@@ -369,67 +704,67 @@ Definition DoSomething: val :=
 Definition standardForLoop: val :=
   rec: "standardForLoop" "s" :=
     let: "sumPtr" := ref (zero_val uint64T) in
-    let: "i" := ref_to uint64T #0 in
-    (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
-      (if: ![uint64T] "i" < slice.len "s"
-      then
-        let: "sum" := ![uint64T] "sumPtr" in
-        let: "x" := SliceGet uint64T "s" (![uint64T] "i") in
-        "sumPtr" <-[uint64T] "sum" + "x";;
-        "i" <-[uint64T] ![uint64T] "i" + #1;;
-        Continue
-      else Break));;
+    (let: "i" := ref_to uint64T #0 in
+     (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
+       (if: ![uint64T] "i" < slice.len "s"
+       then
+         let: "sum" := ![uint64T] "sumPtr" in
+         let: "x" := SliceGet uint64T "s" (![uint64T] "i") in
+         "sumPtr" <-[uint64T] "sum" + "x";;
+         "i" <-[uint64T] ![uint64T] "i" + #1;;
+         Continue
+       else Break)));;
     let: "sum" := ![uint64T] "sumPtr" in
     "sum".
 
 Definition conditionalInLoop: val :=
   rec: "conditionalInLoop" <> :=
-    let: "i" := ref_to uint64T #0 in
-    (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
-      (if: ![uint64T] "i" < #3
-      then
-        DoSomething (#(str"i is small"));;
-        #()
-      else #());;
-      (if: ![uint64T] "i" > #5
-      then Break
-      else
-        "i" <-[uint64T] ![uint64T] "i" + #1;;
-        Continue)).
+    (let: "i" := ref_to uint64T #0 in
+     (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
+       (if: ![uint64T] "i" < #3
+       then
+         DoSomething (#(str"i is small"));;
+         #()
+       else #());;
+       (if: ![uint64T] "i" > #5
+       then Break
+       else
+         "i" <-[uint64T] ![uint64T] "i" + #1;;
+         Continue))).
 
 Definition ImplicitLoopContinue: val :=
   rec: "ImplicitLoopContinue" <> :=
-    let: "i" := ref_to uint64T #0 in
-    (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
-      (if: ![uint64T] "i" < #4
-      then "i" <-[uint64T] #0
-      else #());;
-      Continue).
+    (let: "i" := ref_to uint64T #0 in
+     (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
+       (if: ![uint64T] "i" < #4
+       then "i" <-[uint64T] #0
+       else #());;
+       Continue)).
 
 Definition nestedLoops: val :=
   rec: "nestedLoops" <> :=
-    let: "i" := ref_to uint64T #0 in
-    (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
-      let: "j" := ref_to uint64T #0 in
-      (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
-        (if: #true
-        then Break
-        else
-          "j" <-[uint64T] ![uint64T] "j" + #1;;
-          Continue));;
-      "i" <-[uint64T] ![uint64T] "i" + #1;;
-      Continue).
+    (let: "i" := ref_to uint64T #0 in
+     (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
+       (let: "j" := ref_to uint64T #0 in
+        (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
+          (if: #true
+          then Break
+          else
+            "j" <-[uint64T] ![uint64T] "j" + #1;;
+            Continue)));;
+       "i" <-[uint64T] ![uint64T] "i" + #1;;
+       Continue)).
 
 Definition nestedGoStyleLoops: val :=
   rec: "nestedGoStyleLoops" <> :=
-    let: "i" := ref_to uint64T #0 in
-    (for: (λ: <>, ![uint64T] "i" < #10); (λ: <>, "i" <-[uint64T] ![uint64T] "i" + #1) := λ: <>,
-      let: "j" := ref_to uint64T #0 in
-      (for: (λ: <>, ![uint64T] "j" < ![uint64T] "i"); (λ: <>, "j" <-[uint64T] ![uint64T] "j" + #1) := λ: <>,
-        (if: #true
-        then Break
-        else Continue));;
-      Continue).
+    (let: "i" := ref_to uint64T #0 in
+     (for: (λ: <>, ![uint64T] "i" < #10); (λ: <>, "i" <-[uint64T] ![uint64T] "i" + #1) := λ: <>,
+       (let: "j" := ref_to uint64T #0 in
+        (for: (λ: <>, ![uint64T] "j" < ![uint64T] "i"); (λ: <>, "j" <-[uint64T] ![uint64T] "j" + #1) := λ: <>,
+          (if: #true
+          then Break
+          else Continue)));;
+       Continue)).
 
 Definition sumSlice: val :=
   rec: "sumSlice" "xs" :=
@@ -446,6 +781,87 @@ Definition breakFromLoop: val :=
       then Break
       else Continue)).
 
+Definition blockList: ty := slice.T uint64T.
+
+Definition blockIndex: ty := mapT uint64T.
+
+Definition sumBlockList: val :=
+  rec: "sumBlockList" "bs" "idx" :=
+    let: "sum" := ref (zero_val uint64T) in
+    ForSlice uint64T <> "b" "bs"
+      ("sum" <-[uint64T] ![uint64T] "sum" + "b");;
+    MapIter "idx" (λ: "k" "v",
+      "sum" <-[uint64T] ![uint64T] "sum" + "k" + "v");;
+    ![uint64T] "sum".
+
+Definition useInt: val :=
+  rec: "useInt" "x" :=
+    #().
+
+Definition rangeOverInt: val :=
+  rec: "rangeOverInt" "n" :=
+    ForRange uint64T "i" "n"
+      (DoSomething #(str"counting");;
+      useInt "i");;
+    ForRange uint64T <> #3
+      (DoSomething (#(str"three times"))).
+
+Definition rangeAssign: val :=
+  rec: "rangeAssign" "s" "m" :=
+    let: "last" := ref (zero_val uint64T) in
+    let: "lastVal" := ref (zero_val uint64T) in
+    ForSlice uint64T <> "$val" "s"
+      ("lastVal" <-[uint64T] "$val";;
+      #());;
+    MapIter "m" (λ: "$key" <>,
+      "last" <-[uint64T] "$key";;
+      #());;
+    ![uint64T] "last" + ![uint64T] "lastVal".
+
+Definition reverseBytes: val :=
+  rec: "reverseBytes" "b" :=
+    (let: "i" := ref_to uint64T #0 in
+     let: "j" := ref_to uint64T (slice.len "b" - #1) in
+     (for: (λ: <>, ![uint64T] "i" < ![uint64T] "j"); (λ: <>, let: "$a0" := ![uint64T] "i" + #1 in
+     let: "$a1" := ![uint64T] "j" - #1 in
+     "i" <-[uint64T] "$a0";;
+     "j" <-[uint64T] "$a1") := λ: <>,
+       let: "x" := SliceGet byteT "b" (![uint64T] "i") in
+       SliceSet byteT "b" (![uint64T] "i") (SliceGet byteT "b" (![uint64T] "j"));;
+       SliceSet byteT "b" (![uint64T] "j") "x";;
+       Continue)).
+
+Definition loopWithoutInit: val :=
+  rec: "loopWithoutInit" "n" :=
+    let: "i" := ref (zero_val uint64T) in
+    let: "steps" := ref (zero_val uint64T) in
+    Skip;;
+    (for: (λ: <>, ![uint64T] "i" < "n"); (λ: <>, "i" <-[uint64T] ![uint64T] "i" + #2) := λ: <>,
+      "steps" <-[uint64T] ![uint64T] "steps" + #1;;
+      Continue);;
+    ![uint64T] "steps".
+
+Definition swapInLoopInit: val :=
+  rec: "swapInLoopInit" "i" "j" :=
+    let: "sum" := ref (zero_val uint64T) in
+    (let: "$v0" := "j" in
+     let: "$v1" := "i" in
+     let: "i" := ref_to uint64T "$v0" in
+     let: "j" := ref_to uint64T "$v1" in
+     (for: (λ: <>, ![uint64T] "i" < ![uint64T] "j"); (λ: <>, "i" <-[uint64T] ![uint64T] "i" + #1) := λ: <>,
+       "sum" <-[uint64T] ![uint64T] "sum" + ![uint64T] "i";;
+       Continue));;
+    ![uint64T] "sum".
+
+Definition loopVarScope: val :=
+  rec: "loopVarScope" "i" :=
+    let: "sum" := ref (zero_val uint64T) in
+    (let: "i" := ref_to uint64T #0 in
+     (for: (λ: <>, ![uint64T] "i" < #3); (λ: <>, "i" <-[uint64T] ![uint64T] "i" + #1) := λ: <>,
+       "sum" <-[uint64T] ![uint64T] "sum" + ![uint64T] "i";;
+       Continue));;
+    ![uint64T] "sum" + "i".
+
 (* maps.go *)
 
 Definition clearMap: val :=
@@ -470,6 +886,54 @@ Definition MapTypeAliases: val :=
   rec: "MapTypeAliases" "m1" "m2" :=
     MapInsert "m1" #4 (Fst (MapGet "m2" #0)).
 
+(* method_value.go *)
+
+Module Log.
+  Definition S := struct.decl [
+    "entries" :: slice.T uint64T
+  ].
+End Log.
+
+Definition Log__Append: val :=
+  rec: "Log__Append" "l" "x" :=
+    struct.storeF Log.S "entries" "l" (SliceAppend uint64T (struct.loadF Log.S "entries" "l") "x").
+
+Definition Log__Flush: val :=
+  rec: "Log__Flush" "l" :=
+    struct.storeF Log.S "entries" "l" slice.nil.
+
+Definition applyAll: val :=
+  rec: "applyAll" "f" "xs" :=
+    ForSlice uint64T <> "x" "xs"
+      ("f" "x").
+
+Definition runThunk: val :=
+  rec: "runThunk" "f" :=
+    "f" #().
+
+Definition useMethodValues: val :=
+  rec: "useMethodValues" "l" :=
+    applyAll (Log__Append "l") (SliceSingleton #3);;
+    runThunk (λ: <>, Log__Flush "l").
+
+Definition useMethodExpr: val :=
+  rec: "useMethodExpr" "l" :=
+    let: "appendTo" := Log__Append in
+    "appendTo" "l" #4;;
+    Log__Append "l" #5.
+
+Definition useMethodValueOfVar: val :=
+  rec: "useMethodValueOfVar" <> :=
+    let: "l" := ref_to (refT (struct.t Log.S)) (struct.new Log.S [
+      "entries" ::= slice.nil
+    ]) in
+    let: "f" := (let: "$recv" := ![refT (struct.t Log.S)] "l" in
+     (λ: <>, Log__Flush "$recv")) in
+    "l" <-[refT (struct.t Log.S)] struct.new Log.S [
+      "entries" ::= slice.nil
+    ];;
+    runThunk "f".
+
 (* multiple.go *)
 
 Definition returnTwo: val :=
@@ -485,6 +949,51 @@ Definition multipleVar: val :=
   rec: "multipleVar" "x" "y" :=
     #().
 
+(* mutual_recursion.go *)
+
+(* mutually recursive functions isEven, isOdd *)
+Definition mutual__isEven__isOdd: val :=
+  rec: "mutual__isEven__isOdd" "$fn" :=
+    let isEven := (λ: "x", "mutual__isEven__isOdd" #0 "x")%E in
+    let isOdd := (λ: "x", "mutual__isEven__isOdd" #1 "x")%E in
+    (if: ("$fn" = #0)
+    then
+      (λ: "x", (if: ("x" = #0)
+                then #true
+                else isOdd ("x" - #1)))
+    else
+      (λ: "x", (if: ("x" = #0)
+                then #false
+                else isEven ("x" - #1)))).
+
+Definition isEven: val := (λ: "x", mutual__isEven__isOdd #0 "x").
+
+(* isOdd is defined along with isEven *)
+Definition isOdd: val := (λ: "x", mutual__isEven__isOdd #1 "x").
+
+Module binTree.
+  Definition S := struct.decl [
+    "left" :: refT anyT;
+    "right" :: refT anyT
+  ].
+End binTree.
+
+(* mutually recursive functions binTree__size, childrenSize *)
+Definition mutual__binTree__size__childrenSize: val :=
+  rec: "mutual__binTree__size__childrenSize" "$fn" :=
+    let binTree__size := (λ: "t", "mutual__binTree__size__childrenSize" #0 "t")%E in
+    let childrenSize := (λ: "t", "mutual__binTree__size__childrenSize" #1 "t")%E in
+    (if: ("$fn" = #0)
+    then
+      (λ: "t", (if: ("t" = #null)
+                then #0
+                else #1 + childrenSize "t"))
+    else (λ: "t", binTree__size (struct.loadF binTree.S "left" "t") + binTree__size (struct.loadF binTree.S "right" "t"))).
+
+Definition binTree__size: val := (λ: "t", mutual__binTree__size__childrenSize #0 "t").
+
+Definition childrenSize: val := (λ: "t", mutual__binTree__size__childrenSize #1 "t").
+
 (* nil.go *)
 
 Definition AssignNilSlice: val :=
@@ -495,7 +1004,7 @@ Definition AssignNilSlice: val :=
 Definition AssignNilPointer: val :=
   rec: "AssignNilPointer" <> :=
     let: "s" := NewSlice (refT uint64T) #4 in
-    SliceSet (refT uint64T) "s" #2 slice.nil.
+    SliceSet (refT uint64T) "s" #2 #null.
 
 Definition CompareSliceToNil: val :=
   rec: "CompareSliceToNil" <> :=
@@ -507,6 +1016,44 @@ Definition ComparePointerToNil: val :=
     let: "s" := ref (zero_val uint64T) in
     "s" ≠ #null.
 
+Definition CompareMapToNil: val :=
+  rec: "CompareMapToNil" "m" :=
+    ("m" = #null).
+
+Definition CompareFuncToNil: val :=
+  rec: "CompareFuncToNil" "f" :=
+    #null ≠ "f".
+
+Definition useNils: val :=
+  rec: "useNils" "m" "f" "x" "ps" "s" :=
+    #().
+
+Definition NilValues: val :=
+  rec: "NilValues" <> :=
+    let: "m" := ref_to (mapT boolT) #null in
+    let: "f" := ref_to (arrowT uint64T unitT) #null in
+    let: "x" := ref_to anyT #null in
+    let: "ps" := ref_to (slice.T (refT uint64T)) (NewSlice (refT uint64T) #0) in
+    "ps" <-[slice.T (refT uint64T)] SliceAppend (refT uint64T) (![slice.T (refT uint64T)] "ps") #null;;
+    let: "s" := slice.nil in
+    useNils (![mapT boolT] "m") (![arrowT uint64T unitT] "f") (![anyT] "x") (![slice.T (refT uint64T)] "ps") "s".
+
+Module nilHolder.
+  Definition S := struct.decl [
+    "p" :: refT uint64T;
+    "m" :: mapT boolT
+  ].
+End nilHolder.
+
+Definition NilElements: val :=
+  rec: "NilElements" <> :=
+    let: "xs" := SliceSingleton #null in
+    let: "h" := struct.mk nilHolder.S [
+      "p" ::= #null;
+      "m" ::= #null
+    ] in
+    ("xs", "h").
+
 (* operators.go *)
 
 Definition LogicalOperators: val :=
@@ -570,6 +1117,60 @@ Definition PanicAtTheDisco: val :=
   rec: "PanicAtTheDisco" <> :=
     Panic "disco".
 
+Definition panicWithError: val :=
+  rec: "panicWithError" "err" :=
+    (if: "err" ≠ #null
+    then PanicValue "err"
+    else #()).
+
+Definition panicMessage : expr := #(str"constant message").
+
+Definition panicWithConstant: val :=
+  rec: "panicWithConstant" <> :=
+    Panic ("constant message").
+
+Definition handleRecovered: val :=
+  rec: "handleRecovered" "r" :=
+    #().
+
+Definition recoverPanic: val :=
+  rec: "recoverPanic" <> :=
+    with_defer (zero_val boolT) (λ: "$defers",
+      defer "$defers" (λ: <>, let: "r" := recover #() in
+               (if: "r" ≠ #null
+               then handleRecovered "r"
+               else #()));;
+      panicWithError ErrNotFound;;
+      #true).
+
+Module deferredCounter.
+  Definition S := struct.decl [
+    "mu" :: lockRefT;
+    "count" :: uint64T
+  ].
+End deferredCounter.
+
+Definition deferredCounter__incr: val :=
+  rec: "deferredCounter__incr" "c" :=
+    with_defer (zero_val uint64T) (λ: "$defers",
+      lock.acquire (struct.loadF deferredCounter.S "mu" "c");;
+      (let: "$recv" := struct.loadF deferredCounter.S "mu" "c" in
+       defer "$defers" (λ: <>, lock.release "$recv"));;
+      struct.storeF deferredCounter.S "count" "c" (struct.loadF deferredCounter.S "count" "c" + #1);;
+      struct.loadF deferredCounter.S "count" "c").
+
+Definition useDeferred: val :=
+  rec: "useDeferred" "x" :=
+    #().
+
+Definition deferShadowing: val :=
+  rec: "deferShadowing" <> :=
+    with_defer (zero_val unitT) (λ: "$defers",
+      let: "x" := #1 in
+      (let: "x" := #2 in
+       defer "$defers" (λ: <>, useDeferred "x"));;
+      useDeferred "x").
+
 (* reassign.go *)
 
 Module composite.
@@ -594,6 +1195,12 @@ Definition ReassignVars: val :=
     ];;
     "x" <-[uint64T] struct.get composite.S "a" (![struct.t composite.S] "z").
 
+(* renamed_imports.go *)
+
+Definition renamedDiskRead: val :=
+  rec: "renamedDiskRead" "a" :=
+    disk.Read "a".
+
 (* replicated_disk.go *)
 
 Module Block.
@@ -654,19 +1261,19 @@ Definition ReplicatedDiskWrite: val :=
 
 Definition ReplicatedDiskRecover: val :=
   rec: "ReplicatedDiskRecover" <> :=
-    let: "a" := ref_to uint64T #0 in
-    (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
-      (if: ![uint64T] "a" > DiskSize
-      then Break
-      else
-        let: ("v", "ok") := TwoDiskRead Disk1 (![uint64T] "a") in
-        (if: "ok"
-        then
-          TwoDiskWrite Disk2 (![uint64T] "a") "v";;
-          #()
-        else #());;
-        "a" <-[uint64T] ![uint64T] "a" + #1;;
-        Continue)).
+    (let: "a" := ref_to uint64T #0 in
+     (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
+       (if: ![uint64T] "a" > DiskSize
+       then Break
+       else
+         let: ("v", "ok") := TwoDiskRead Disk1 (![uint64T] "a") in
+         (if: "ok"
+         then
+           TwoDiskWrite Disk2 (![uint64T] "a") "v";;
+           #()
+         else #());;
+         "a" <-[uint64T] ![uint64T] "a" + #1;;
+         Continue))).
 
 (* slices.go *)
 
@@ -734,15 +1341,54 @@ Definition threadCode: val :=
 
 Definition loopSpawn: val :=
   rec: "loopSpawn" <> :=
-    let: "i" := ref_to uint64T #0 in
-    (for: (λ: <>, ![uint64T] "i" < #10); (λ: <>, "i" <-[uint64T] ![uint64T] "i" + #1) := λ: <>,
-      let: "i" := ![uint64T] "i" in
-      Fork (threadCode "i");;
-      Continue);;
-    let: "dummy" := ref_to boolT #true in
-    (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
-      "dummy" <-[boolT] ~ (![boolT] "dummy");;
-      Continue).
+    (let: "i" := ref_to uint64T #0 in
+     (for: (λ: <>, ![uint64T] "i" < #10); (λ: <>, "i" <-[uint64T] ![uint64T] "i" + #1) := λ: <>,
+       let: "i" := ![uint64T] "i" in
+       Fork (threadCode "i");;
+       Continue));;
+    (let: "dummy" := ref_to boolT #true in
+     (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
+       "dummy" <-[boolT] ~ (![boolT] "dummy");;
+       Continue)).
+
+Module worker.
+  Definition S := struct.decl [
+    "id" :: uint64T
+  ].
+End worker.
+
+Definition worker__background: val :=
+  rec: "worker__background" "w" :=
+    #().
+
+Definition worker__process: val :=
+  rec: "worker__process" "w" "job" "data" :=
+    #().
+
+Definition spawnWithArgs: val :=
+  rec: "spawnWithArgs" "w" :=
+    Fork (threadCode #3);;
+    (let: "i" := ref_to uint64T #0 in
+     (for: (λ: <>, ![uint64T] "i" < #2); (λ: <>, "i" <-[uint64T] ![uint64T] "i" + #1) := λ: <>,
+       (let: "$a0" := ![uint64T] "i" in
+        Fork (threadCode "$a0"));;
+       Continue));;
+    (let: "$go" := (λ: <>, worker__background "w") in
+     Fork ("$go" #()));;
+    (let: "$go" := worker__process "w" in
+     (let: "$a0" := struct.loadF worker.S "id" "w" in
+      Fork ("$go" "$a0" slice.nil)));;
+    (let: "x" := struct.loadF worker.S "id" "w" in
+     Fork (threadCode "x"));;
+    let: "f" := threadCode in
+    Fork ("f" #1).
+
+Definition spawnShadowing: val :=
+  rec: "spawnShadowing" <> :=
+    let: "x" := #1 in
+    (let: "x" := #2 in
+     Fork (threadCode "x"));;
+    threadCode "x".
 
 (* strings.go *)
 
@@ -750,9 +1396,49 @@ Definition stringAppend: val :=
   rec: "stringAppend" "s" "x" :=
     #(str"prefix ") + "s" + #(str" ") + uint64_to_string "x".
 
-Definition stringLength: val :=
-  rec: "stringLength" "s" :=
-    strLen "s".
+Definition stringIndex: val :=
+  rec: "stringIndex" "s" "i" :=
+    StringGet "s" "i".
+
+Definition stringSlices: val :=
+  rec: "stringSlices" "s" :=
+    StringSkip "s" #1 + StringTake "s" #2 + StringSubslice "s" #1 #3.
+
+Definition stringCompare: val :=
+  rec: "stringCompare" "s1" "s2" :=
+    (StringLt "s1" "s2") || (StringGe "s1" "s2") && ("s1" ≠ #(str"foo")).
+
+Definition stringCountSlashes: val :=
+  rec: "stringCountSlashes" "s" :=
+    let: "n" := ref_to uint64T #0 in
+    ForSlice byteT <> "b" (Data.stringToBytes "s")
+      (if: ("b" = #(U8 47))
+      then "n" <-[uint64T] ![uint64T] "n" + #1
+      else #());;
+    ![uint64T] "n".
+
+Definition stringLastRune: val :=
+  rec: "stringLastRune" "s" :=
+    let: "last" := ref_to uint32T (#(U32 0)) in
+    ForString "i" "r" "s"
+      (if: ("i" > #0) && ("r" ≠ #(U32 233))
+      then "last" <-[uint32T] "r"
+      else #());;
+    ![uint32T] "last".
+
+Definition isDigitRune: val :=
+  rec: "isDigitRune" "r" :=
+    ("r" ≥ #(U32 48)) && ("r" ≤ #(U32 57)).
+
+Definition countDigits: val :=
+  rec: "countDigits" "s" :=
+    let: "n" := ref_to uint64T #0 in
+    ForString <> "r" "s"
+      (let: "c" := ref_to uint32T "r" in
+      (if: isDigitRune (![uint32T] "c")
+      then "n" <-[uint64T] ![uint64T] "n" + #1
+      else #()));;
+    ![uint64T] "n".
 
 (* struct_method.go *)
 
@@ -867,6 +1553,55 @@ Definition makeLock: val :=
     let: "l" := lock.new #() in
     DoSomeLocking "l".
 
+Module cache.
+  Definition S := struct.decl [
+    "mu" :: rwlockRefT;
+    "entries" :: mapT uint64T
+  ].
+End cache.
+
+Definition cache__get: val :=
+  rec: "cache__get" "c" "k" :=
+    rwlock.read_acquire (struct.loadF cache.S "mu" "c");;
+    let: "v" := Fst (MapGet (struct.loadF cache.S "entries" "c") "k") in
+    rwlock.read_release (struct.loadF cache.S "mu" "c");;
+    "v".
+
+Definition cache__put: val :=
+  rec: "cache__put" "c" "k" "v" :=
+    rwlock.write_acquire (struct.loadF cache.S "mu" "c");;
+    MapInsert (struct.loadF cache.S "entries" "c") "k" "v";;
+    rwlock.write_release (struct.loadF cache.S "mu" "c").
+
+Definition waitForWorkers: val :=
+  rec: "waitForWorkers" "n" :=
+    let: "wg" := waitgroup.new #() in
+    (let: "i" := ref_to uint64T #0 in
+     (for: (λ: <>, ![uint64T] "i" < "n"); (λ: <>, "i" <-[uint64T] ![uint64T] "i" + #1) := λ: <>,
+       waitgroup.add "wg" #1;;
+       Fork (waitgroup.done "wg");;
+       Continue));;
+    waitgroup.wait "wg".
+
+Module lazyInit.
+  Definition S := struct.decl [
+    "once" :: onceRefT;
+    "val" :: refT uint64T
+  ].
+End lazyInit.
+
+Definition lazyInit__get: val :=
+  rec: "lazyInit__get" "l" :=
+    once.do (struct.loadF lazyInit.S "once" "l") (λ: <>, struct.loadF lazyInit.S "val" "l" <-[uint64T] #1);;
+    ![uint64T] (struct.loadF lazyInit.S "val" "l").
+
+Definition newLazyInit: val :=
+  rec: "newLazyInit" <> :=
+    struct.new lazyInit.S [
+      "once" ::= once.new #();
+      "val" ::= ref (zero_val uint64T)
+    ].
+
 (* type_alias.go *)
 
 Definition u64: ty := uint64T.
@@ -881,3 +1616,67 @@ Definition convertToAlias: val :=
   rec: "convertToAlias" <> :=
     let: "x" := #2 in
     "x".
+
+(* type_method.go *)
+
+Definition Inum: ty := uint64T.
+
+Definition Inum__IsRoot: val :=
+  rec: "Inum__IsRoot" "i" :=
+    ("i" = #1).
+
+Definition Bitmap: ty := slice.T byteT.
+
+Definition Bitmap__Get: val :=
+  rec: "Bitmap__Get" "bm" "i" :=
+    (SliceGet byteT "bm" ("i" `quot` #8) `and` #(U8 1) ≪ "i" `rem` #8) ≠ #(U8 0).
+
+Definition Bitmap__Set: val :=
+  rec: "Bitmap__Set" "bm" "i" :=
+    SliceSet byteT "bm" ("i" `quot` #8) (SliceGet byteT "bm" ("i" `quot` #8) `or` #(U8 1) ≪ "i" `rem` #8).
+
+Definition useTypeMethods: val :=
+  rec: "useTypeMethods" "bm" "i" :=
+    (if: Inum__IsRoot "i"
+    then #true
+    else
+      Bitmap__Set "bm" "i";;
+      Bitmap__Get "bm" "i").
+
+Definition Counter: ty := uint64T.
+
+Definition Counter__Inc: val :=
+  rec: "Counter__Inc" "c" :=
+    "c" <-[Counter] ![Counter] "c" + #1.
+
+Definition Counter__Get: val :=
+  rec: "Counter__Get" "c" :=
+    "c".
+
+Definition useCounter: val :=
+  rec: "useCounter" "p" :=
+    let: "c" := ref_to Counter #0 in
+    Counter__Inc "c";;
+    Counter__Inc "p";;
+    Counter__Get (![Counter] "c") + Counter__Get (![Counter] "p").
+
+(* variadic.go *)
+
+Definition sumAll: val :=
+  rec: "sumAll" "xs" :=
+    let: "sum" := ref_to uint64T #0 in
+    ForSlice uint64T <> "x" "xs"
+      ("sum" <-[uint64T] ![uint64T] "sum" + "x");;
+    ![uint64T] "sum".
+
+Definition sumWithBase: val :=
+  rec: "sumWithBase" "base" "xs" :=
+    "base" + sumAll "xs".
+
+Definition useVariadic: val :=
+  rec: "useVariadic" <> :=
+    sumAll slice.nil + sumAll (slice.literal uint64T [#1]) + sumWithBase #2 (slice.literal uint64T [#3; #4]).
+
+Definition appendMultiple: val :=
+  rec: "appendMultiple" "s" :=
+    SliceAppendSlice uint64T "s" (slice.literal uint64T [#1; #2; #3]).
@@ -0,0 +1,21 @@
+package unittest
+
+func sumAll(xs ...uint64) uint64 {
+	var sum = uint64(0)
+	for _, x := range xs {
+		sum += x
+	}
+	return sum
+}
+
+func sumWithBase(base uint64, xs ...uint64) uint64 {
+	return base + sumAll(xs...)
+}
+
+func useVariadic() uint64 {
+	return sumAll() + sumAll(1) + sumWithBase(2, 3, 4)
+}
+
+func appendMultiple(s []uint64) []uint64 {
+	return append(s, 1, 2, 3)
+}
@@ -169,15 +169,15 @@ Hint Resolve getLogEntry_t : types.
 (* applyLog assumes we are running sequentially *)
 Definition applyLog: val :=
   rec: "applyLog" "d" "length" :=
-    let: "i" := ref_to uint64T #0 in
-    (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
-      (if: ![uint64T] "i" < "length"
-      then
-        let: ("a", "v") := getLogEntry "d" (![uint64T] "i") in
-        disk.Write (logLength + "a") "v";;
-        "i" <-[uint64T] ![uint64T] "i" + #1;;
-        Continue
-      else Break)).
+    (let: "i" := ref_to uint64T #0 in
+     (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
+       (if: ![uint64T] "i" < "length"
+       then
+         let: ("a", "v") := getLogEntry "d" (![uint64T] "i") in
+         disk.Write (logLength + "a") "v";;
+         "i" <-[uint64T] ![uint64T] "i" + #1;;
+         Continue
+       else Break))).
 Theorem applyLog_t: ⊢ applyLog : (disk.Disk -> uint64T -> unitT).
 Proof. typecheck. Qed.
 Hint Resolve applyLog_t : types.
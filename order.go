@@ -0,0 +1,321 @@
+package goose
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+	"strings"
+
+	"github.com/tchajed/goose/internal/coq"
+)
+
+// A declUnit is a top-level Go declaration along with its translation, which
+// is the granularity at which declarations are reordered.
+type declUnit struct {
+	file int
+	// the declaration (nil for a placeholder for the start of a file)
+	decl  ast.Decl
+	decls []coq.Decl
+	// the identifier to report errors about this declaration at
+	name *ast.Ident
+	// the package-level objects this declaration defines
+	defs []types.Object
+	// the declaration is translated to axioms, so only its signature matters
+	axiom bool
+	// the declaration failed to translate (and was replaced by axioms)
+	failed bool
+	// the units this declaration refers to
+	deps []int
+}
+
+// declName finds the first name defined by a top-level declaration
+func declName(d ast.Decl) *ast.Ident {
+	switch d := d.(type) {
+	case *ast.FuncDecl:
+		return d.Name
+	case *ast.GenDecl:
+		for _, s := range d.Specs {
+			switch s := s.(type) {
+			case *ast.TypeSpec:
+				return s.Name
+			case *ast.ValueSpec:
+				return s.Names[0]
+			}
+		}
+	}
+	return nil
+}
+
+// defines gives the package-level objects defined by a top-level declaration
+func (ctx Ctx) defines(d ast.Decl) []types.Object {
+	var objs []types.Object
+	add := func(ident *ast.Ident) {
+		if obj := ctx.info.Defs[ident]; obj != nil {
+			objs = append(objs, obj)
+		}
+	}
+	switch d := d.(type) {
+	case *ast.FuncDecl:
+		add(d.Name)
+	case *ast.GenDecl:
+		for _, s := range d.Specs {
+			switch s := s.(type) {
+			case *ast.TypeSpec:
+				add(s.Name)
+			case *ast.ValueSpec:
+				for _, name := range s.Names {
+					add(name)
+				}
+			}
+		}
+	}
+	return objs
+}
+
+// isPackageLevel checks if obj is a top-level declaration (or method) of the
+// package being translated
+func (ctx Ctx) isPackageLevel(obj types.Object) bool {
+	if obj == nil || obj.Pkg() == nil || obj.Pkg().Path() != ctx.pkgPath {
+		return false
+	}
+	if f, ok := obj.(*types.Func); ok &&
+		f.Type().(*types.Signature).Recv() != nil {
+		return true
+	}
+	return obj.Parent() == obj.Pkg().Scope()
+}
+
+// namedTypes calls add on the named types that the translation of a value of
+// type t might refer to
+func namedTypes(t types.Type, add func(obj types.Object)) {
+	switch t := t.(type) {
+	case *types.Alias:
+		add(t.Obj())
+		namedTypes(types.Unalias(t), add)
+	case *types.Named:
+		// the underlying type is covered by the dependencies of t's declaration
+		add(t.Obj())
+	case *types.Pointer:
+		namedTypes(t.Elem(), add)
+	case *types.Slice:
+		namedTypes(t.Elem(), add)
+	case *types.Array:
+		namedTypes(t.Elem(), add)
+	case *types.Map:
+		namedTypes(t.Key(), add)
+		namedTypes(t.Elem(), add)
+	}
+}
+
+// uses gives the package-level objects a declaration refers to, either
+// explicitly or through the types of its expressions (for example, a field
+// access needs the struct's declaration)
+//
+// Skipped declarations refer to nothing, and functions declared as axioms
+// only to the types in their signature.
+func (ctx Ctx) uses(d ast.Decl, axiom bool) []types.Object {
+	if directive(d) == directiveSkip {
+		return nil
+	}
+	nodes := []ast.Node{d}
+	if d, ok := d.(*ast.FuncDecl); ok && axiom {
+		nodes = []ast.Node{d.Type}
+		if d.Recv != nil {
+			nodes = append(nodes, d.Recv)
+		}
+	}
+	seen := make(map[types.Object]bool)
+	var objs []types.Object
+	add := func(obj types.Object) {
+		if ctx.isPackageLevel(obj) && !seen[obj] {
+			seen[obj] = true
+			objs = append(objs, obj)
+		}
+	}
+	for _, node := range nodes {
+		ast.Inspect(node, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok {
+				add(ctx.info.Uses[ident])
+			}
+			if e, ok := n.(ast.Expr); ok {
+				if tv, ok := ctx.info.Types[e]; ok {
+					namedTypes(tv.Type, add)
+				}
+			}
+			return true
+		})
+	}
+	return objs
+}
+
+// components computes the strongly connected components of the dependency
+// graph between units (using Tarjan's algorithm), with each component's
+// units in source order.
+func components(units []declUnit) [][]int {
+	index := make([]int, len(units))
+	lowlink := make([]int, len(units))
+	onStack := make([]bool, len(units))
+	for i := range index {
+		index[i] = -1
+	}
+	var stack []int
+	var sccs [][]int
+	next := 0
+	var connect func(v int)
+	connect = func(v int) {
+		index[v] = next
+		lowlink[v] = next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range units[v].deps {
+			if index[w] < 0 {
+				connect(w)
+				lowlink[v] = min(lowlink[v], lowlink[w])
+			} else if onStack[w] {
+				lowlink[v] = min(lowlink[v], index[w])
+			}
+		}
+		if lowlink[v] == index[v] {
+			var scc []int
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				scc = append(scc, w)
+				if w == v {
+					break
+				}
+			}
+			sort.Ints(scc)
+			sccs = append(sccs, scc)
+		}
+	}
+	for v := range units {
+		if index[v] < 0 {
+			connect(v)
+		}
+	}
+	return sccs
+}
+
+// mutualFuncs combines a group of mutually recursive functions into a single
+// declaration
+func mutualFuncs(units []declUnit, scc []int) (decl coq.MutualFuncDecl, ok bool) {
+	var names []string
+	for _, u := range scc {
+		if len(units[u].decls) != 1 {
+			return coq.MutualFuncDecl{}, false
+		}
+		f, ok := units[u].decls[0].(coq.FuncDecl)
+		if !ok {
+			return coq.MutualFuncDecl{}, false
+		}
+		// the bundle is not a typed function, so neither are its projections
+		f.AddTypes = false
+		decl.Funcs = append(decl.Funcs, f)
+		names = append(names, f.Name)
+	}
+	decl.Name = "mutual__" + strings.Join(names, "__")
+	return decl, true
+}
+
+// checkCycle reports a group of declarations that refer to each other, which
+// cannot be translated to a sequence of Coq definitions
+func (ctx Ctx) checkCycle(units []declUnit, scc []int) error {
+	var names []string
+	for _, u := range scc {
+		names = append(names, units[u].name.Name)
+	}
+	return ctx.unsupportedError(units[scc[0]].name,
+		"mutually recursive declarations %s", strings.Join(names, ", "))
+}
+
+// checkMutualOpaque reports goose:opaque on a function in a group of mutually
+// recursive functions, since its body is still visible through the bundle
+func (ctx Ctx) checkMutualOpaque(units []declUnit, scc []int) []error {
+	var errs []error
+	for _, u := range scc {
+		if directive(units[u].decl) == directiveOpaque {
+			errs = append(errs, ctx.unsupportedError(units[u].name,
+				"goose:opaque on mutually recursive function %s", units[u].name.Name))
+		}
+	}
+	return errs
+}
+
+// checkNotSkipped reports a declaration that refers to a declaration omitted
+// with goose:skip, since its translation would be missing a definition
+func (ctx Ctx) checkNotSkipped(u declUnit, obj types.Object) error {
+	return ctx.unsupportedError(u.name, "%s refers to %s, which is skipped (goose:skip)",
+		u.name.Name, obj.Name())
+}
+
+// orderDecls arranges the translated units in dependency order, so that every
+// Coq definition comes after the definitions it refers to.
+//
+// Declarations are kept in source order as far as possible: a declaration is
+// moved earlier only if something before it depends on it.
+func (ctx Ctx) orderDecls(units []declUnit) (ordered []declUnit, errs []error) {
+	owner := make(map[types.Object]int)
+	for i, u := range units {
+		for _, obj := range u.defs {
+			owner[obj] = i
+		}
+	}
+	for i := range units {
+		if units[i].decl == nil {
+			continue
+		}
+		for _, obj := range ctx.uses(units[i].decl, units[i].axiom) {
+			j, ok := owner[obj]
+			if !ok || j == i {
+				continue
+			}
+			if directive(units[j].decl) == directiveSkip {
+				errs = append(errs, ctx.checkNotSkipped(units[i], obj))
+				continue
+			}
+			units[i].deps = append(units[i].deps, j)
+		}
+		sort.Ints(units[i].deps)
+	}
+
+	sccs := components(units)
+	component := make([]int, len(units))
+	for c, scc := range sccs {
+		for _, u := range scc {
+			component[u] = c
+		}
+	}
+	emitted := make([]bool, len(sccs))
+	var emit func(c int)
+	emit = func(c int) {
+		if emitted[c] {
+			return
+		}
+		emitted[c] = true
+		for _, u := range sccs[c] {
+			for _, dep := range units[u].deps {
+				emit(component[dep])
+			}
+		}
+		if len(sccs[c]) > 1 {
+			if decl, ok := mutualFuncs(units, sccs[c]); ok {
+				errs = append(errs, ctx.checkMutualOpaque(units, sccs[c])...)
+				group := units[sccs[c][0]]
+				group.decls = []coq.Decl{decl}
+				ordered = append(ordered, group)
+				return
+			}
+			errs = append(errs, ctx.checkCycle(units, sccs[c]))
+		}
+		for _, u := range sccs[c] {
+			ordered = append(ordered, units[u])
+		}
+	}
+	for u := range units {
+		emit(component[u])
+	}
+	return
+}
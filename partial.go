@@ -0,0 +1,88 @@
+package goose
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+
+	"github.com/tchajed/goose/internal/coq"
+)
+
+// errorComment describes a translation error, for the comment on the axioms
+// that replace a declaration in a partial translation
+func errorComment(err error) string {
+	if cerr, ok := err.(*ConversionError); ok {
+		return fmt.Sprintf("goose could not translate this declaration:\n[%s]: %s (%s)",
+			cerr.Category, cerr.Message, cerr.GoCode)
+	}
+	return fmt.Sprintf("goose could not translate this declaration:\n%s", err)
+}
+
+// failedDecls gives axioms for the names defined by a declaration that
+// failed to translate, so that the rest of a partial translation can still
+// refer to them
+//
+// The axioms are typed as far as their types can be translated.
+func (ctx Ctx) failedDecls(d ast.Decl, err error) []coq.Decl {
+	comment := errorComment(err)
+	switch d := d.(type) {
+	case *ast.FuncDecl:
+		var fd coq.FuncDecl
+		if err := catchError(func() { fd = ctx.funcSignature(d) }); err != nil {
+			fd = coq.FuncDecl{Name: d.Name.Name}
+			if d.Recv != nil {
+				recv, ok := recvTypeName(d)
+				if !ok {
+					// without the receiver's type there is no name to declare
+					return []coq.Decl{coq.NewComment(comment)}
+				}
+				fd.Name = coq.TypeMethod(recv, d.Name.Name)
+			}
+		}
+		fd.Comment = comment
+		return []coq.Decl{coq.AxiomDecl{Func: fd}}
+	case *ast.GenDecl:
+		var decls []coq.Decl
+		for _, s := range d.Specs {
+			switch s := s.(type) {
+			case *ast.TypeSpec:
+				_, isStruct := s.Type.(*ast.StructType)
+				decls = append(decls, coq.TypeAxiomDecl{
+					Name:    s.Name.Name,
+					Struct:  isStruct,
+					Comment: comment,
+				})
+			case *ast.ValueSpec:
+				if d.Tok == token.VAR && !ctx.isSentinelError(s) {
+					// other global variables have no translation
+					continue
+				}
+				for _, name := range s.Names {
+					decls = append(decls, ctx.constAxiom(name, comment))
+				}
+			}
+			// only the first axiom needs the comment
+			if len(decls) > 0 {
+				comment = ""
+			}
+		}
+		if len(decls) == 0 {
+			return []coq.Decl{coq.NewComment(comment)}
+		}
+		return decls
+	}
+	return []coq.Decl{coq.NewComment(comment)}
+}
+
+// constAxiom declares a constant that failed to translate, typed if its type
+// can be translated
+func (ctx Ctx) constAxiom(name *ast.Ident, comment string) coq.ConstAxiomDecl {
+	cd := coq.ConstAxiomDecl{Name: name.Name, Comment: comment}
+	err := catchError(func() {
+		cd.Type = ctx.coqTypeOfType(name, ctx.typeOf(name))
+	})
+	if err == nil {
+		cd.AddTypes = ctx.Config.TypeCheck
+	}
+	return cd
+}
@@ -0,0 +1,5 @@
+package example
+
+func modifyArray(a [5]uint64) {
+	a[0] = 1 // ERROR array a is not addressable
+}
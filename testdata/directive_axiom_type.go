@@ -0,0 +1,6 @@
+package example
+
+//goose:axiom
+type handle struct { // ERROR goose:axiom only applies to functions
+	fd uint64
+}
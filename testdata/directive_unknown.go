@@ -0,0 +1,6 @@
+package example
+
+//goose:trusted
+func trusted() uint64 { // ERROR unknown directive goose:trusted
+	return 0
+}
@@ -0,0 +1,7 @@
+package example
+
+import "github.com/tchajed/goose/machine/disk" // ERROR requires the disk FFI
+
+func readBlock(a uint64) disk.Block {
+	return disk.Read(a)
+}
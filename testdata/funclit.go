@@ -0,0 +1,8 @@
+package example
+
+func apply(x uint64) uint64 {
+	f := func(y uint64) uint64 { // ERROR function literal outside of go, defer or sync.Once.Do
+		return y + 1
+	}
+	return f(x)
+}
@@ -2,4 +2,8 @@ package example
 
 // sneaky import
 
-import _ "sync/atomic" // ERROR renaming
+import . "sync" // ERROR dot import
+
+func lockUnqualified(m *Mutex) {
+	m.Lock()
+}
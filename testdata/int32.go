@@ -0,0 +1,5 @@
+package example
+
+func decrement(x int32) int32 { // ERROR basic type int32
+	return x - 5
+}
@@ -0,0 +1,5 @@
+package example
+
+func sameValue(x interface{}, y interface{}) bool {
+	return x == y // ERROR comparison of interface values
+}
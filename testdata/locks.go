@@ -2,9 +2,6 @@ package example
 
 import "sync"
 
-type hasJustCondVar struct {
-	cond sync.Cond // ERROR sync.Cond
-}
-type hasJustMutex struct {
-	m sync.Mutex // ERROR sync.Mutex
+func lockCopy(m sync.Mutex) {
+	m.Lock() // ERROR m is not addressable
 }
@@ -0,0 +1,9 @@
+package example
+
+type listA struct { // ERROR mutually recursive declarations listA, listB
+	next *listB
+}
+
+type listB struct {
+	next *listA
+}
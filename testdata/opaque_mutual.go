@@ -0,0 +1,16 @@
+package example
+
+//goose:opaque
+func even(n uint64) bool { // ERROR goose:opaque on mutually recursive function even
+	if n == 0 {
+		return true
+	}
+	return odd(n - 1)
+}
+
+func odd(n uint64) bool {
+	if n == 0 {
+		return false
+	}
+	return even(n - 1)
+}
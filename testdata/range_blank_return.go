@@ -0,0 +1,10 @@
+package example
+
+func firstNonZero(xs []uint64) uint64 {
+	for _, x := range xs {
+		if x != 0 {
+			return x // ERROR return in loop
+		}
+	}
+	return 0
+}
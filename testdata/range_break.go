@@ -0,0 +1,12 @@
+package example
+
+func firstZero(xs []uint64) uint64 {
+	var idx = uint64(0)
+	for i, x := range xs {
+		if x == 0 {
+			idx = uint64(i)
+			break // ERROR break in range loop
+		}
+	}
+	return idx
+}
@@ -0,0 +1,8 @@
+package example
+
+func loopOnce(n uint64) uint64 {
+	for range n {
+		return 1 // ERROR return in loop
+	}
+	return 0
+}
@@ -0,0 +1,9 @@
+package example
+
+import "errors"
+
+func message() string {
+	return "not found"
+}
+
+var ErrNotFound = errors.New(message()) // ERROR package-level error with a non-constant message
@@ -0,0 +1,10 @@
+package example
+
+//goose:skip
+func helper(x uint64) uint64 {
+	return x + 1
+}
+
+func user(x uint64) uint64 { // ERROR user refers to helper, which is skipped
+	return helper(x)
+}